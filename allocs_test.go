@@ -0,0 +1,95 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import "testing"
+
+type allocsUser struct {
+	Name   string  `taint:"name"`
+	Age    int     `taint:"age,min=0"`
+	Score  float64 `taint:"score"`
+	Active bool    `taint:"active"`
+}
+
+type allocsUserDTO struct {
+	Name   string
+	Age    int
+	Score  float64
+	Active bool
+}
+
+func TestInjectAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops values randomly with the race detector")
+	}
+	var (
+		dstUser   allocsUser
+		dstString string
+		dstInt    int
+		dstInts   []int
+		dstArray  [4]string
+		dstMap    map[string]string
+	)
+	for _, tc := range []struct {
+		name string
+		src  interface{}
+		dst  interface{}
+		max  float64
+	}{
+		{
+			name: "struct to struct",
+			src:  &allocsUserDTO{Name: "Alice", Age: 300, Score: 1.5, Active: true},
+			dst:  &dstUser,
+		},
+		{
+			name: "map to struct",
+			src:  map[string]interface{}{"name": "Alice", "age": 300, "score": 1.5, "active": true},
+			dst:  &dstUser,
+		},
+		{
+			name: "string",
+			src:  "Alice",
+			dst:  &dstString,
+		},
+		{
+			name: "int",
+			src:  300,
+			dst:  &dstInt,
+		},
+		{
+			name: "interface slice to array",
+			src:  []interface{}{"a", "b", "c", "d"},
+			dst:  &dstArray,
+		},
+		{
+			name: "slice",
+			src:  []int{1, 2, 3, 4},
+			dst:  &dstInts,
+			// The new slice.
+			max: 2,
+		},
+		{
+			name: "map",
+			src:  map[string]string{"a": "b", "c": "d"},
+			dst:  &dstMap,
+			// The new map, and the key and the value that are copied into
+			// it.
+			max: 4,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			in := NewInjector()
+			allocs := testing.AllocsPerRun(100, func() {
+				if err := in.Inject(tc.src, tc.dst); err != nil {
+					t.Fatal(err)
+				}
+			})
+			if allocs > tc.max {
+				t.Errorf("got %v allocations, want at most %v", allocs, tc.max)
+			}
+		})
+	}
+}
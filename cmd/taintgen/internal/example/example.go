@@ -0,0 +1,37 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package example holds types with methods generated by the taintgen command
+// that are tested for parity with the reflection in the taint package.
+package example
+
+//go:generate go run resenje.org/taint/cmd/taintgen -type User -copy User:UserDTO,UserDTO:User
+
+// User is a struct with methods generated by the taintgen command.
+type User struct {
+	ID       int    `taint:"id,required"`
+	Name     string `taint:"name"`
+	Email    string
+	Age      uint8   `taint:"age"`
+	Score    float64 `taint:"score"`
+	Active   bool    `taint:"active"`
+	Level    Level   `taint:"level"`
+	Password string  `taint:"-"`
+	note     string
+}
+
+// UserDTO is a struct with a different shape that User is copied to.
+type UserDTO struct {
+	ID       int64  `taint:"id,required"`
+	FullName string `taint:"Name"`
+	Email    string `taint:"email"`
+	Age      int    `taint:"age"`
+	Score    string `taint:"score"`
+	Level    string `taint:"level"`
+	Password string `taint:"password"`
+}
+
+// Level is a named string type.
+type Level string
@@ -0,0 +1,205 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package example
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"resenje.org/taint"
+)
+
+// reflective is an Injector with a field hook that does not change values,
+// so that generated methods are not used.
+var reflective = taint.NewInjector(taint.WithFieldHook(func(_ context.Context, _ string, src, _ interface{}) (interface{}, error) {
+	return src, nil
+}))
+
+func TestInjectFromMapParity(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  map[string]interface{}
+	}{
+		{
+			name: "exact types",
+			src: map[string]interface{}{
+				"id":       1,
+				"name":     "alice",
+				"Email":    "alice@example.com",
+				"age":      uint8(30),
+				"score":    9.5,
+				"active":   true,
+				"level":    Level("gold"),
+				"Password": "secret",
+				"-":        "secret",
+				"note":     "note",
+			},
+		},
+		{
+			name: "converted types",
+			src: map[string]interface{}{
+				"id":    int64(1),
+				"age":   "30",
+				"score": 9,
+				"level": "gold",
+			},
+		},
+		{
+			name: "missing required",
+			src: map[string]interface{}{
+				"name": "alice",
+			},
+		},
+		{
+			name: "overflow",
+			src: map[string]interface{}{
+				"id":  1,
+				"age": 300,
+			},
+		},
+		{
+			name: "nil value",
+			src: map[string]interface{}{
+				"id":   1,
+				"name": nil,
+			},
+		},
+		{
+			name: "invalid type",
+			src: map[string]interface{}{
+				"id":     1,
+				"active": []string{"yes"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var want User
+			wantErr := reflective.Inject(tc.src, &want)
+
+			var got User
+			gotErr := got.InjectFromMap(tc.src)
+			assertParity(t, got, want, gotErr, wantErr)
+
+			got = User{}
+			gotErr = taint.Inject(tc.src, &got)
+			assertParity(t, got, want, gotErr, wantErr)
+		})
+	}
+}
+
+func TestToMapParity(t *testing.T) {
+	u := User{
+		ID:       1,
+		Name:     "alice",
+		Email:    "alice@example.com",
+		Age:      30,
+		Score:    9.5,
+		Active:   true,
+		Level:    "gold",
+		Password: "secret",
+		note:     "note",
+	}
+	var want map[string]interface{}
+	if err := reflective.Inject(u, &want); err != nil {
+		t.Fatal(err)
+	}
+
+	got := u.ToMap()
+	assertParity(t, got, want, nil, nil)
+
+	got = nil
+	if err := taint.Inject(u, &got); err != nil {
+		t.Fatal(err)
+	}
+	assertParity(t, got, want, nil, nil)
+}
+
+func TestCopyToParity(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  interface{}
+		dst  func() interface{}
+		copy func(dst interface{}) error
+	}{
+		{
+			name: "user to dto",
+			src: User{
+				ID:       1,
+				Name:     "alice",
+				Email:    "alice@example.com",
+				Age:      30,
+				Score:    9.5,
+				Level:    "gold",
+				Password: "secret",
+			},
+			dst: func() interface{} { return new(UserDTO) },
+		},
+		{
+			name: "dto to user",
+			src: UserDTO{
+				ID:       1,
+				FullName: "alice",
+				Email:    "alice@example.com",
+				Age:      30,
+				Score:    "9.5",
+				Level:    "gold",
+				Password: "secret",
+			},
+			dst: func() interface{} { return new(User) },
+		},
+		{
+			name: "dto overflow",
+			src: UserDTO{
+				ID:  1,
+				Age: 300,
+			},
+			dst: func() interface{} { return new(User) },
+		},
+		{
+			name: "dto invalid score",
+			src: UserDTO{
+				ID:    1,
+				Score: "high",
+			},
+			dst: func() interface{} { return new(User) },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			want := tc.dst()
+			wantErr := reflective.Inject(tc.src, want)
+
+			got := tc.dst()
+			var gotErr error
+			switch s := tc.src.(type) {
+			case User:
+				gotErr = s.CopyTo(got.(*UserDTO))
+			case UserDTO:
+				gotErr = s.CopyTo(got.(*User))
+			}
+			assertParity(t, got, want, gotErr, wantErr)
+
+			got = tc.dst()
+			gotErr = taint.Inject(tc.src, got)
+			assertParity(t, got, want, gotErr, wantErr)
+		})
+	}
+}
+
+func assertParity(t *testing.T, got, want interface{}, gotErr, wantErr error) {
+	t.Helper()
+
+	if fmt.Sprint(gotErr) != fmt.Sprint(wantErr) {
+		t.Errorf("got error %v, want %v", gotErr, wantErr)
+	}
+	if reflect.TypeOf(gotErr) != reflect.TypeOf(wantErr) {
+		t.Errorf("got error type %T, want %T", gotErr, wantErr)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,138 @@
+// Code generated by taintgen; DO NOT EDIT.
+
+package example
+
+import (
+	"resenje.org/taint"
+)
+
+// InjectFromMap injects values from the map into fields of User in the same
+// way as taint.Inject, without reflection.
+func (x *User) InjectFromMap(m map[string]any) error {
+	if v, ok := m["id"]; ok {
+		if w, ok := v.(int); ok {
+			x.ID = w
+		} else if err := taint.Inject(v, &x.ID); err != nil {
+			return err
+		}
+	} else {
+		return &taint.FieldRequiredError{FieldName: "id"}
+	}
+	if v, ok := m["name"]; ok {
+		if w, ok := v.(string); ok {
+			x.Name = w
+		} else if err := taint.Inject(v, &x.Name); err != nil {
+			return err
+		}
+	}
+	if v, ok := m["Email"]; ok {
+		if w, ok := v.(string); ok {
+			x.Email = w
+		} else if err := taint.Inject(v, &x.Email); err != nil {
+			return err
+		}
+	}
+	if v, ok := m["age"]; ok {
+		if w, ok := v.(uint8); ok {
+			x.Age = w
+		} else if err := taint.Inject(v, &x.Age); err != nil {
+			return err
+		}
+	}
+	if v, ok := m["score"]; ok {
+		if w, ok := v.(float64); ok {
+			x.Score = w
+		} else if err := taint.Inject(v, &x.Score); err != nil {
+			return err
+		}
+	}
+	if v, ok := m["active"]; ok {
+		if w, ok := v.(bool); ok {
+			x.Active = w
+		} else if err := taint.Inject(v, &x.Active); err != nil {
+			return err
+		}
+	}
+	if v, ok := m["level"]; ok {
+		if w, ok := v.(Level); ok {
+			x.Level = w
+		} else if err := taint.Inject(v, &x.Level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ToMap returns fields of User as a map in the same way as taint.Inject,
+// without reflection.
+func (x User) ToMap() map[string]any {
+	m := make(map[string]any, 7)
+	m["id"] = x.ID
+	m["name"] = x.Name
+	m["Email"] = x.Email
+	m["age"] = x.Age
+	m["score"] = x.Score
+	m["active"] = x.Active
+	m["level"] = x.Level
+	return m
+}
+
+// CopyTo injects fields of User into fields of UserDTO in the same way as
+// taint.Inject, without reflection.
+func (x User) CopyTo(o *UserDTO) error {
+	if err := taint.Inject(x.ID, &o.ID); err != nil {
+		return err
+	}
+	o.FullName = x.Name
+	o.Email = x.Email
+	if err := taint.Inject(x.Age, &o.Age); err != nil {
+		return err
+	}
+	if err := taint.Inject(x.Score, &o.Score); err != nil {
+		return err
+	}
+	if err := taint.Inject(x.Level, &o.Level); err != nil {
+		return err
+	}
+	o.Password = x.Password
+	return nil
+}
+
+// CopyToAny calls CopyTo if the destination is *UserDTO, so that it is used
+// by taint.Inject. It returns false for destinations of other types.
+func (x User) CopyToAny(dst any) (bool, error) {
+	o, ok := dst.(*UserDTO)
+	if !ok {
+		return false, nil
+	}
+	return true, x.CopyTo(o)
+}
+
+// CopyTo injects fields of UserDTO into fields of User in the same way as
+// taint.Inject, without reflection.
+func (x UserDTO) CopyTo(o *User) error {
+	if err := taint.Inject(x.ID, &o.ID); err != nil {
+		return err
+	}
+	o.Email = x.Email
+	if err := taint.Inject(x.Age, &o.Age); err != nil {
+		return err
+	}
+	if err := taint.Inject(x.Score, &o.Score); err != nil {
+		return err
+	}
+	if err := taint.Inject(x.Level, &o.Level); err != nil {
+		return err
+	}
+	return nil
+}
+
+// CopyToAny calls CopyTo if the destination is *User, so that it is used
+// by taint.Inject. It returns false for destinations of other types.
+func (x UserDTO) CopyToAny(dst any) (bool, error) {
+	o, ok := dst.(*User)
+	if !ok {
+		return false, nil
+	}
+	return true, x.CopyTo(o)
+}
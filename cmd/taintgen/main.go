@@ -0,0 +1,427 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command taintgen generates methods for struct types that inject values
+// without reflection, following the same taint struct tag rules as the
+// resenje.org/taint package. Generated methods are used by taint.Inject
+// when they are present.
+//
+// For every type provided with the -type flag, the InjectFromMap method
+// injects values from a map[string]any into the struct fields and the ToMap
+// method returns struct fields as a map[string]any. For every pair provided
+// with the -copy flag in the Source:Destination form, the CopyTo method of
+// the source type injects its fields into the destination struct, and the
+// CopyToAny method implements the taint.Copier interface with it. Every
+// source type can be copied to only one destination type.
+//
+// Usage:
+//
+//	//go:generate go run resenje.org/taint/cmd/taintgen -type User -copy User:UserDTO
+//
+// Struct fields must be of boolean, numeric or string types that do not
+// implement encoding.TextUnmarshaler or taint hook interfaces, and struct tags
+// may contain only key names, "-" and the required option. Other fields and
+// tag options are reported as errors, as they require the reflection.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	tagKey     = "taint"
+	taintPath  = "resenje.org/taint"
+	taintAlias = "taint"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of struct type names")
+	copyPairs := flag.String("copy", "", "comma-separated list of Source:Destination struct type name pairs")
+	output := flag.String("output", "", "output file name; default <dir>/<type>_taint.go")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: taintgen [flags] [directory]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	if err := run(dir, *typeNames, *copyPairs, *output); err != nil {
+		fmt.Fprintln(os.Stderr, "taintgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, typeNames, copyPairs, output string) error {
+	c := config{
+		types: split(typeNames),
+	}
+	for _, pair := range split(copyPairs) {
+		src, dst, ok := strings.Cut(pair, ":")
+		if !ok || src == "" || dst == "" {
+			return fmt.Errorf("invalid copy pair %q", pair)
+		}
+		c.copies = append(c.copies, [2]string{src, dst})
+	}
+	if len(c.types) == 0 && len(c.copies) == 0 {
+		return errors.New("no types provided")
+	}
+	if output == "" {
+		var name string
+		if len(c.types) > 0 {
+			name = c.types[0]
+		} else {
+			name = c.copies[0][0]
+		}
+		output = filepath.Join(dir, strings.ToLower(name)+"_taint.go")
+	}
+	src, err := generate(dir, filepath.Base(output), c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(output, src, 0o666)
+}
+
+func split(s string) (l []string) {
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			l = append(l, e)
+		}
+	}
+	return l
+}
+
+// config holds names of types to generate methods for.
+type config struct {
+	types  []string
+	copies [][2]string
+}
+
+// generate returns formatted source code with generated methods for the
+// package in the directory. The file with the output name is not loaded,
+// so that the previously generated code does not affect the new one.
+func generate(dir, output string, c config) ([]byte, error) {
+	pkg, err := load(dir, output)
+	if err != nil {
+		return nil, err
+	}
+	g := &generator{
+		pkg:     pkg,
+		imports: make(map[string]string),
+	}
+	for _, name := range c.types {
+		s, err := g.lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		g.injectFromMap(s)
+		g.toMap(s)
+	}
+	copied := make(map[string]string)
+	for _, pair := range c.copies {
+		// The CopyTo method can be declared only once for every type.
+		if dst, ok := copied[pair[0]]; ok {
+			return nil, fmt.Errorf("type %s is copied to both %s and %s, only one destination is supported", pair[0], dst, pair[1])
+		}
+		copied[pair[0]] = pair[1]
+		src, err := g.lookup(pair[0])
+		if err != nil {
+			return nil, err
+		}
+		dst, err := g.lookup(pair[1])
+		if err != nil {
+			return nil, err
+		}
+		if err := g.copyTo(src, dst); err != nil {
+			return nil, err
+		}
+		g.copyToAny(src, dst)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by taintgen; DO NOT EDIT.\n\npackage %s\n\n", pkg.Name())
+	paths := make([]string, 0, len(g.imports))
+	for p := range g.imports {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	if len(paths) > 0 {
+		buf.WriteString("import (\n")
+		for _, p := range paths {
+			if name := g.imports[p]; name != path.Base(p) {
+				fmt.Fprintf(&buf, "%s ", name)
+			}
+			fmt.Fprintf(&buf, "%q\n", p)
+		}
+		buf.WriteString(")\n")
+	}
+	buf.Write(g.buf.Bytes())
+	return format.Source(buf.Bytes())
+}
+
+// load parses and type-checks Go files of the package in the directory.
+func load(dir, output string) (*types.Package, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		if name == output {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+	}
+	return conf.Check(bp.ImportPath, fset, files, nil)
+}
+
+// structType is a struct type with fields that generated methods handle.
+type structType struct {
+	name   string
+	fields []field
+	// exported holds all exported fields, including the ones that are
+	// skipped by the struct tag, as the taint package looks up source
+	// struct fields by their names.
+	exported []*types.Var
+}
+
+// field is an exported struct field that is not skipped by the struct tag.
+type field struct {
+	name     string
+	key      string
+	required bool
+	typ      types.Type
+}
+
+type generator struct {
+	pkg     *types.Package
+	imports map[string]string
+	buf     bytes.Buffer
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// qualifier returns the package name for types from other packages and
+// records the import.
+func (g *generator) qualifier(p *types.Package) string {
+	if p == g.pkg {
+		return ""
+	}
+	g.imports[p.Path()] = p.Name()
+	return p.Name()
+}
+
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, g.qualifier)
+}
+
+func (g *generator) taint() string {
+	g.imports[taintPath] = taintAlias
+	return taintAlias
+}
+
+// lookup returns the struct type with the name from the package, or an error
+// if the type is not a struct or if some of its fields are not supported.
+func (g *generator) lookup(name string) (*structType, error) {
+	obj, ok := g.pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("type %s not found", name)
+	}
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("type %s is not a struct", name)
+	}
+	s := &structType{
+		name: name,
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		if f.Embedded() {
+			return nil, fmt.Errorf("%s.%s: embedded fields are not supported", name, f.Name())
+		}
+		if !f.Exported() {
+			continue
+		}
+		s.exported = append(s.exported, f)
+		key, options := parseTag(st.Tag(i))
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = f.Name()
+		}
+		var required bool
+		for _, o := range options {
+			if o != "required" {
+				return nil, fmt.Errorf("%s.%s: tag option %q is not supported", name, f.Name(), o)
+			}
+			required = true
+		}
+		if err := check(name, f); err != nil {
+			return nil, err
+		}
+		s.fields = append(s.fields, field{
+			name:     f.Name(),
+			key:      key,
+			required: required,
+			typ:      f.Type(),
+		})
+	}
+	return s, nil
+}
+
+// check returns an error if the field type is not supported.
+func check(name string, f *types.Var) error {
+	t := types.TypeString(f.Type(), types.RelativeTo(f.Pkg()))
+	if !supported(f.Type()) {
+		return fmt.Errorf("%s.%s: type %s is not supported", name, f.Name(), t)
+	}
+	for _, m := range []string{"BeforeInject", "AfterInject", "UnmarshalText"} {
+		if hasMethod(f.Type(), m) {
+			return fmt.Errorf("%s.%s: type %s with %s method is not supported", name, f.Name(), t, m)
+		}
+	}
+	return nil
+}
+
+// parseTag returns the key name and options from the struct tag in the same
+// way as the taint package.
+func parseTag(tag string) (key string, options []string) {
+	t := reflect.StructTag(tag).Get(tagKey)
+	if t == "" {
+		return "", nil
+	}
+	l := strings.Split(t, ",")
+	key, options = l[0], l[1:]
+	if strings.Contains(key, "=") {
+		options = append([]string{key}, options...)
+		key = ""
+	}
+	return key, options
+}
+
+// supported returns true for boolean, numeric and string types.
+func supported(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return false
+	}
+	info := b.Info()
+	return info&types.IsUntyped == 0 && info&types.IsComplex == 0 &&
+		info&(types.IsBoolean|types.IsNumeric|types.IsString) != 0
+}
+
+// hasMethod returns true if the type or the pointer to it has the method.
+func hasMethod(t types.Type, name string) bool {
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), false, nil, name)
+	_, ok := obj.(*types.Func)
+	return ok
+}
+
+func (g *generator) injectFromMap(s *structType) {
+	g.printf("\n// InjectFromMap injects values from the map into fields of %s in the same\n", s.name)
+	g.printf("// way as taint.Inject, without reflection.\n")
+	g.printf("func (x *%s) InjectFromMap(m map[string]any) error {\n", s.name)
+	for _, f := range s.fields {
+		g.printf("if v, ok := m[%q]; ok {\n", f.key)
+		g.printf("if w, ok := v.(%s); ok {\nx.%s = w\n", g.typeString(f.typ), f.name)
+		g.printf("} else if err := %s.Inject(v, &x.%s); err != nil {\nreturn err\n}\n", g.taint(), f.name)
+		if f.required {
+			g.printf("} else {\nreturn &%s.FieldRequiredError{FieldName: %q}\n", g.taint(), f.key)
+		}
+		g.printf("}\n")
+	}
+	g.printf("return nil\n}\n")
+}
+
+func (g *generator) toMap(s *structType) {
+	g.printf("\n// ToMap returns fields of %s as a map in the same way as taint.Inject,\n", s.name)
+	g.printf("// without reflection.\n")
+	g.printf("func (x %s) ToMap() map[string]any {\n", s.name)
+	g.printf("m := make(map[string]any, %d)\n", len(s.fields))
+	for _, f := range s.fields {
+		g.printf("m[%q] = x.%s\n", f.key, f.name)
+	}
+	g.printf("return m\n}\n")
+}
+
+func (g *generator) copyTo(src, dst *structType) error {
+	g.printf("\n// CopyTo injects fields of %s into fields of %s in the same way as\n", src.name, dst.name)
+	g.printf("// taint.Inject, without reflection.\n")
+	g.printf("func (x %s) CopyTo(o *%s) error {\n", src.name, dst.name)
+	for _, f := range dst.fields {
+		s := src.field(f.name)
+		if s == nil {
+			s = src.field(f.key)
+		}
+		if s == nil {
+			s = src.field(strings.ToUpper(f.key[:1]) + f.key[1:])
+		}
+		if s == nil {
+			if f.required {
+				g.printf("return &%s.FieldRequiredError{FieldName: %q}\n}\n", g.taint(), f.key)
+				return nil
+			}
+			continue
+		}
+		if err := check(src.name, s); err != nil {
+			return err
+		}
+		if types.Identical(s.Type(), f.typ) {
+			g.printf("o.%s = x.%s\n", f.name, s.Name())
+			continue
+		}
+		g.printf("if err := %s.Inject(x.%s, &o.%s); err != nil {\nreturn err\n}\n", g.taint(), s.Name(), f.name)
+	}
+	g.printf("return nil\n}\n")
+	return nil
+}
+
+func (g *generator) copyToAny(src, dst *structType) {
+	g.printf("\n// CopyToAny calls CopyTo if the destination is *%s, so that it is used\n", dst.name)
+	g.printf("// by taint.Inject. It returns false for destinations of other types.\n")
+	g.printf("func (x %s) CopyToAny(dst any) (bool, error) {\n", src.name)
+	g.printf("o, ok := dst.(*%s)\nif !ok {\nreturn false, nil\n}\n", dst.name)
+	g.printf("return true, x.CopyTo(o)\n}\n")
+}
+
+// field returns the exported field with the Go name, or nil if it does not
+// exist.
+func (s *structType) field(name string) *types.Var {
+	for _, f := range s.exported {
+		if f.Name() == name {
+			return f
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateExample(t *testing.T) {
+	dir := filepath.Join("internal", "example")
+	got, err := generate(dir, "user_taint.go", config{
+		types:  []string{"User"},
+		copies: [][2]string{{"User", "UserDTO"}, {"UserDTO", "User"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join(dir, "user_taint.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("generated code is not up to date, run go generate in %s:\n%s", dir, got)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  string
+		c    config
+		err  string
+	}{
+		{
+			name: "not found",
+			src:  "type T struct{}",
+			c:    config{types: []string{"U"}},
+			err:  "type U not found",
+		},
+		{
+			name: "not struct",
+			src:  "type T int",
+			c:    config{types: []string{"T"}},
+			err:  "type T is not a struct",
+		},
+		{
+			name: "unsupported type",
+			src:  "type T struct{ A []string }",
+			c:    config{types: []string{"T"}},
+			err:  "T.A: type []string is not supported",
+		},
+		{
+			name: "unsupported option",
+			src:  "type T struct{ A int `taint:\"a,min=1\"` }",
+			c:    config{types: []string{"T"}},
+			err:  `T.A: tag option "min=1" is not supported`,
+		},
+		{
+			name: "embedded",
+			src:  "type E struct{}\ntype T struct{ E }",
+			c:    config{types: []string{"T"}},
+			err:  "T.E: embedded fields are not supported",
+		},
+		{
+			name: "unmarshaler",
+			src:  "type S string\nfunc (*S) UnmarshalText([]byte) error { return nil }\ntype T struct{ A S }",
+			c:    config{types: []string{"T"}},
+			err:  "T.A: type S with UnmarshalText method is not supported",
+		},
+		{
+			name: "unsupported source field",
+			src:  "type S struct{ A []int `taint:\"-\"` }\ntype T struct{ A int }",
+			c:    config{copies: [][2]string{{"S", "T"}}},
+			err:  "S.A: type []int is not supported",
+		},
+		{
+			name: "repeated copy source",
+			src:  "type A struct{ X int }\ntype B struct{ X int }\ntype C struct{ X int }",
+			c:    config{copies: [][2]string{{"A", "B"}, {"A", "C"}}},
+			err:  "type A is copied to both B and C, only one destination is supported",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte("package p\n\n"+tc.src+"\n"), 0o666); err != nil {
+				t.Fatal(err)
+			}
+			_, err := generate(dir, "p_taint.go", tc.c)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("got error %v, want %q", err, tc.err)
+			}
+		})
+	}
+}
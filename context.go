@@ -0,0 +1,37 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+// contextCheckInterval is the number of injected values after which the
+// context is checked for cancellation.
+const contextCheckInterval = 64
+
+// checkContext returns ContextError if the context is canceled. The context
+// is checked on every contextCheckInterval call.
+func (in *injection) checkContext(path string) error {
+	return in.checkContextN(path, 1)
+}
+
+// checkContextN returns ContextError if the context is canceled after n
+// values are injected at once. The context is checked if the number of
+// injected values reaches a multiple of contextCheckInterval.
+func (in *injection) checkContextN(path string, n int) error {
+	if in.done == nil {
+		return nil
+	}
+	steps := in.steps
+	in.steps += n
+	if steps/contextCheckInterval == in.steps/contextCheckInterval {
+		return nil
+	}
+	if err := in.ctx.Err(); err != nil {
+		return &ContextError{
+			Path: path,
+			Err:  err,
+		}
+	}
+	return nil
+}
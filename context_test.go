@@ -0,0 +1,148 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestInjectContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var d []int
+	err := InjectContext(ctx, []int{1, 2, 3}, &d)
+	var cerr *ContextError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("Expected ContextError, but got %#v", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+	if d != nil {
+		t.Errorf("destination %#v is modified", d)
+	}
+}
+
+func TestInjectContextCanceledDuringInjection(t *testing.T) {
+	type Record struct {
+		ID int
+	}
+	s := make([]interface{}, 1000)
+	for i := range s {
+		s[i] = map[string]interface{}{"ID": i}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	in := NewInjector(WithFieldHook(func(ctx context.Context, path string, src, dst interface{}) (interface{}, error) {
+		if src == 500 {
+			cancel()
+		}
+		return src, nil
+	}))
+
+	var d []Record
+	err := in.InjectContext(ctx, s, &d)
+	var cerr *ContextError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("Expected ContextError, but got %#v", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+	if cerr.Path == "" {
+		t.Error("context error path is empty")
+	}
+	if d[999].ID != 0 {
+		t.Errorf("injection is not stopped at %s", cerr.Path)
+	}
+}
+
+func TestInjectContextCanceledDirect(t *testing.T) {
+	values := make([]interface{}, 1000)
+	entries := make(map[string]int, 1000)
+	for i := range values {
+		values[i] = i
+		entries[strconv.Itoa(i)] = i
+	}
+	ints := make([]int, 1000)
+	for _, tc := range []struct {
+		name string
+		src  interface{}
+		dst  interface{}
+	}{
+		{
+			name: "copy",
+			src:  map[string]interface{}{"Trigger": "x", "Values": ints},
+			dst: &struct {
+				Trigger string
+				Values  []int
+			}{},
+		},
+		{
+			name: "elements",
+			src:  map[string]interface{}{"Trigger": "x", "Values": values},
+			dst: &struct {
+				Trigger string
+				Values  []int
+			}{},
+		},
+		{
+			name: "map",
+			src:  map[string]interface{}{"Trigger": "x", "Values": entries},
+			dst: &struct {
+				Trigger string
+				Values  map[string]int
+			}{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			in := NewInjector(WithFieldHook(func(ctx context.Context, path string, src, dst interface{}) (interface{}, error) {
+				if path == "Trigger" {
+					cancel()
+				}
+				return src, nil
+			}))
+			err := in.InjectContext(ctx, tc.src, tc.dst)
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("got error %v, want %v", err, context.Canceled)
+			}
+		})
+	}
+}
+
+type contextKey struct{}
+
+func TestInjectContextValues(t *testing.T) {
+	type Config struct {
+		Name  string
+		Owner string
+	}
+	ctx := context.WithValue(context.Background(), contextKey{}, "alice")
+	in := NewInjector(WithFieldHook(func(ctx context.Context, path string, src, dst interface{}) (interface{}, error) {
+		if path == "Owner" {
+			return ctx.Value(contextKey{}), nil
+		}
+		return src, nil
+	}))
+	s := map[string]interface{}{
+		"Name":  "config",
+		"Owner": "",
+	}
+	var d Config
+	if err := in.InjectContext(ctx, s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Owner != "alice" {
+		t.Errorf("got owner %q, want %q", d.Owner, "alice")
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"context"
+	"encoding"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// Converter converts the source value to a value of the destination type that
+// the converter is registered for with WithConverter option. It receives the
+// context of the injection.
+type Converter func(ctx context.Context, src interface{}) (interface{}, error)
+
+func (in *injection) convert(c Converter, srcValue, dstValue reflect.Value, path string) error {
+	var src interface{}
+	if srcValue.IsValid() && srcValue.CanInterface() {
+		src = srcValue.Interface()
+	}
+	v, err := c(in.ctx, src)
+	if err != nil {
+		return &ConvertError{
+			Path: path,
+			Type: dstValue.Type(),
+			Err:  err,
+		}
+	}
+	value := reflect.ValueOf(v)
+	if !value.IsValid() {
+		return injectNil(value, dstValue)
+	}
+	if !value.Type().AssignableTo(dstValue.Type()) {
+		return &InvalidTypeError{
+			TypeSrc: value.Type(),
+			TypeDst: dstValue.Type(),
+		}
+	}
+	dstValue.Set(value)
+	return nil
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	stringType          = reflect.TypeOf("")
+)
+
+// WithValueConversion configures Injector to convert source values of basic
+// kinds that are not assignable to destinations in the same way as map keys
+// are converted, for example to parse strings into numbers and booleans.
+// Without it, such values are converted only when they are injected from
+// sources returned by EnvSource and FlagSource.
+func WithValueConversion() Option {
+	return func(in *Injector) {
+		in.valueConversion = true
+	}
+}
+
+// converts returns true if source values of basic kinds are converted to the
+// destination type.
+func (in *injection) converts() bool {
+	return in.injectingKey || in.valueConversion || in.converting
+}
+
+// unmarshalText sets the destination that implements encoding.TextUnmarshaler
+// from a string or a byte slice source. It returns false if the source or the
+// destination do not support such conversion.
+func unmarshalText(srcValue, dstValue reflect.Value, path string) (handled bool, err error) {
+	if !reflect.PtrTo(dstValue.Type()).Implements(textUnmarshalerType) {
+		return false, nil
+	}
+	var text []byte
+	switch {
+	case srcValue.Kind() == reflect.String:
+		text = []byte(srcValue.String())
+	case srcValue.Kind() == reflect.Slice && srcValue.Type().Elem().Kind() == reflect.Uint8:
+		text = srcValue.Bytes()
+	default:
+		return false, nil
+	}
+	if err := dstValue.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText(text); err != nil {
+		return true, &ConvertError{
+			Path: path,
+			Type: dstValue.Type(),
+			Err:  err,
+		}
+	}
+	return true, nil
+}
+
+// convertBasic sets the destination of a basic kind to the converted source
+// value. Values are converted between named types with the same underlying
+// kind, between numeric kinds if the value can be represented exactly, from
+// strings to numbers and booleans by parsing them, and from numbers, booleans
+// and encoding.TextMarshaler values to strings. It returns false if the
+// value can not be converted.
+func convertBasic(srcValue, dstValue reflect.Value) bool {
+	srcKind := srcValue.Kind()
+	switch dstValue.Kind() {
+	case reflect.String:
+		switch {
+		case srcKind == reflect.String:
+			dstValue.SetString(srcValue.String())
+		case isInt(srcKind):
+			dstValue.SetString(strconv.FormatInt(srcValue.Int(), 10))
+		case isUint(srcKind):
+			dstValue.SetString(strconv.FormatUint(srcValue.Uint(), 10))
+		case isFloat(srcKind):
+			dstValue.SetString(strconv.FormatFloat(srcValue.Float(), 'g', -1, srcValue.Type().Bits()))
+		case srcKind == reflect.Bool:
+			dstValue.SetString(strconv.FormatBool(srcValue.Bool()))
+		case srcValue.Type().Implements(textMarshalerType):
+			text, err := srcValue.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return false
+			}
+			dstValue.SetString(string(text))
+		default:
+			return false
+		}
+		return true
+	case reflect.Bool:
+		switch srcKind {
+		case reflect.Bool:
+			dstValue.SetBool(srcValue.Bool())
+		case reflect.String:
+			b, err := strconv.ParseBool(srcValue.String())
+			if err != nil {
+				return false
+			}
+			dstValue.SetBool(b)
+		default:
+			return false
+		}
+		return true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		switch {
+		case isInt(srcKind):
+			n = srcValue.Int()
+		case isUint(srcKind):
+			u := srcValue.Uint()
+			if u > math.MaxInt64 {
+				return false
+			}
+			n = int64(u)
+		case isFloat(srcKind):
+			f := srcValue.Float()
+			if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+				return false
+			}
+			n = int64(f)
+		case srcKind == reflect.String:
+			var err error
+			n, err = strconv.ParseInt(srcValue.String(), 10, 64)
+			if err != nil {
+				return false
+			}
+		default:
+			return false
+		}
+		if dstValue.OverflowInt(n) {
+			return false
+		}
+		dstValue.SetInt(n)
+		return true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var n uint64
+		switch {
+		case isInt(srcKind):
+			i := srcValue.Int()
+			if i < 0 {
+				return false
+			}
+			n = uint64(i)
+		case isUint(srcKind):
+			n = srcValue.Uint()
+		case isFloat(srcKind):
+			f := srcValue.Float()
+			if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+				return false
+			}
+			n = uint64(f)
+		case srcKind == reflect.String:
+			var err error
+			n, err = strconv.ParseUint(srcValue.String(), 10, 64)
+			if err != nil {
+				return false
+			}
+		default:
+			return false
+		}
+		if dstValue.OverflowUint(n) {
+			return false
+		}
+		dstValue.SetUint(n)
+		return true
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch {
+		case isInt(srcKind):
+			f = float64(srcValue.Int())
+		case isUint(srcKind):
+			f = float64(srcValue.Uint())
+		case isFloat(srcKind):
+			f = srcValue.Float()
+		case srcKind == reflect.String:
+			var err error
+			f, err = strconv.ParseFloat(srcValue.String(), dstValue.Type().Bits())
+			if err != nil {
+				return false
+			}
+		default:
+			return false
+		}
+		if dstValue.OverflowFloat(f) {
+			return false
+		}
+		dstValue.SetFloat(f)
+		return true
+	}
+	return false
+}
+
+func isInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUint(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func isFloat(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
@@ -0,0 +1,237 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConverter(t *testing.T) {
+	type Config struct {
+		Timeout  time.Duration
+		Timeouts []time.Duration
+		Body     io.Reader
+		Name     string
+	}
+	ctx := context.WithValue(context.Background(), contextKey{}, time.Second)
+	in := NewInjector(
+		WithConverter(time.Duration(0), func(ctx context.Context, src interface{}) (interface{}, error) {
+			switch v := src.(type) {
+			case string:
+				return time.ParseDuration(v)
+			case int:
+				return time.Duration(v) * ctx.Value(contextKey{}).(time.Duration), nil
+			}
+			return nil, fmt.Errorf("invalid duration %v", src)
+		}),
+		WithConverter((*io.Reader)(nil), func(_ context.Context, src interface{}) (interface{}, error) {
+			return strings.NewReader(fmt.Sprint(src)), nil
+		}),
+	)
+	s := map[string]interface{}{
+		"Timeout":  "1m",
+		"Timeouts": []interface{}{"1s", 2},
+		"Body":     "body",
+		"Name":     "name",
+	}
+	var d Config
+	if err := in.InjectContext(ctx, s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Timeout != time.Minute {
+		t.Errorf("got timeout %v, want %v", d.Timeout, time.Minute)
+	}
+	if len(d.Timeouts) != 2 || d.Timeouts[0] != time.Second || d.Timeouts[1] != 2*time.Second {
+		t.Errorf("got timeouts %v", d.Timeouts)
+	}
+	body, err := io.ReadAll(d.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "body" {
+		t.Errorf("got body %q, want %q", body, "body")
+	}
+	if d.Name != "name" {
+		t.Errorf("got name %q, want %q", d.Name, "name")
+	}
+}
+
+func TestConvertError(t *testing.T) {
+	errInvalid := errors.New("invalid")
+	in := NewInjector(WithConverter(time.Duration(0), func(_ context.Context, src interface{}) (interface{}, error) {
+		return nil, errInvalid
+	}))
+	s := map[string]interface{}{
+		"Timeout": "1m",
+	}
+	var d struct {
+		Timeout time.Duration
+	}
+	err := in.Inject(s, &d)
+	var cerr *ConvertError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("Expected ConvertError, but got %#v", err)
+	}
+	if cerr.Path != "Timeout" {
+		t.Errorf("got path %q, want %q", cerr.Path, "Timeout")
+	}
+	if !errors.Is(err, errInvalid) {
+		t.Errorf("got error %v, want %v", err, errInvalid)
+	}
+}
+
+type MyKey string
+
+func TestInjectMapKeyConversion(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		src      interface{}
+		dst      interface{}
+		expected interface{}
+	}{
+		{
+			name:     "named string",
+			src:      map[string]int{"a": 1, "b": 2},
+			dst:      new(map[MyKey]int),
+			expected: map[MyKey]int{"a": 1, "b": 2},
+		},
+		{
+			name: "interface keys",
+			src: map[interface{}]interface{}{
+				"a": map[interface{}]interface{}{"b": 1},
+				1:   map[interface{}]interface{}{2: 3},
+			},
+			dst: new(map[string]map[string]int),
+			expected: map[string]map[string]int{
+				"a": {"b": 1},
+				"1": {"2": 3},
+			},
+		},
+		{
+			name:     "string to int",
+			src:      map[string]string{"1": "one", "-2": "minus two"},
+			dst:      new(map[int]string),
+			expected: map[int]string{1: "one", -2: "minus two"},
+		},
+		{
+			name:     "int to string",
+			src:      map[uint8]bool{1: true},
+			dst:      new(map[string]bool),
+			expected: map[string]bool{"1": true},
+		},
+		{
+			name: "text unmarshaler",
+			src:  map[string]bool{"127.0.0.1": true, "::1": false},
+			dst:  new(map[netip.Addr]bool),
+			expected: map[netip.Addr]bool{
+				netip.MustParseAddr("127.0.0.1"): true,
+				netip.MustParseAddr("::1"):       false,
+			},
+		},
+		{
+			name:     "text marshaler",
+			src:      map[netip.Addr]bool{netip.MustParseAddr("127.0.0.1"): true},
+			dst:      new(map[string]bool),
+			expected: map[string]bool{"127.0.0.1": true},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := Inject(tc.src, tc.dst); err != nil {
+				t.Fatal(err)
+			}
+			d := reflect.ValueOf(tc.dst).Elem().Interface()
+			if !reflect.DeepEqual(d, tc.expected) {
+				t.Errorf("%T destination %#v is not set to %#v", d, d, tc.expected)
+			}
+		})
+	}
+}
+
+func TestKeyCollisionError(t *testing.T) {
+	s := map[interface{}]int{
+		1:   1,
+		"1": 2,
+	}
+	var d map[string]int
+	err := Inject(s, &d)
+	var cerr *KeyCollisionError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("Expected KeyCollisionError, but got %#v", err)
+	}
+	if cerr.Key != "1" {
+		t.Errorf("got key %#v, want %#v", cerr.Key, "1")
+	}
+}
+
+func TestInjectBasicConversion(t *testing.T) {
+	type Level int
+	type Config struct {
+		Port    int
+		Ratio   float32
+		Count   uint16
+		Enabled bool
+		Name    MyKey
+		ID      string
+		Level   Level
+		Addr    netip.Addr
+	}
+	s := map[string]interface{}{
+		"Port":    "8080",
+		"Ratio":   1,
+		"Count":   42.0,
+		"Enabled": "true",
+		"Name":    "name",
+		"ID":      int64(12),
+		"Level":   int64(3),
+		"Addr":    []byte("10.0.0.1"),
+	}
+	expected := Config{
+		Port:    8080,
+		Ratio:   1,
+		Count:   42,
+		Enabled: true,
+		Name:    "name",
+		ID:      "12",
+		Level:   3,
+		Addr:    netip.MustParseAddr("10.0.0.1"),
+	}
+	var d Config
+	if err := NewInjector(WithValueConversion()).Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+
+	for _, src := range []map[string]interface{}{
+		{"Port": "8080"},
+		{"ID": 65},
+		{"Enabled": "1"},
+	} {
+		err := Inject(src, new(Config))
+		var terr *InvalidTypeError
+		if !errors.As(err, &terr) {
+			t.Errorf("Expected InvalidTypeError for %v, but got %#v", src, err)
+		}
+	}
+}
+
+func TestUnmarshalTextError(t *testing.T) {
+	var d netip.Addr
+	err := Inject("not an address", &d)
+	var cerr *ConvertError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("Expected ConvertError, but got %#v", err)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import "reflect"
+
+// visit identifies a source pointer, map or slice that is injected into a
+// destination of a specific type.
+type visit struct {
+	ptr     uintptr
+	len     int
+	srcType reflect.Type
+	dstType reflect.Type
+}
+
+// newVisit returns the visit for the source value if it is a reference that
+// can be a part of a cycle.
+func newVisit(v reflect.Value, dstType reflect.Type) (visit, bool) {
+	var l int
+	switch v.Kind() {
+	case reflect.Ptr:
+		// Pointers to distinct zero-size values may be equal.
+		if v.Type().Elem().Size() == 0 {
+			return visit{}, false
+		}
+	case reflect.Map:
+		if v.Len() == 0 {
+			return visit{}, false
+		}
+	case reflect.Slice:
+		l = v.Len()
+		if l == 0 || v.Type().Elem().Size() == 0 {
+			return visit{}, false
+		}
+	default:
+		return visit{}, false
+	}
+	p := v.Pointer()
+	if p == 0 {
+		return visit{}, false
+	}
+	return visit{
+		ptr:     p,
+		len:     l,
+		srcType: v.Type(),
+		dstType: dstType,
+	}, true
+}
+
+// sharedReference returns the destination pointer, map or slice that was
+// already injected from the same source reference, if sharing of references
+// is preserved.
+func (in *injection) sharedReference(key visit, dstValue reflect.Value) (reflect.Value, bool) {
+	if in.shared == nil {
+		return reflect.Value{}, false
+	}
+	switch dstValue.Kind() {
+	case reflect.Map, reflect.Slice:
+		if p, ok := in.shared[key]; ok {
+			return p.Elem(), true
+		}
+	case reflect.Ptr:
+		if p, ok := in.shared[key]; ok {
+			return p.Elem(), true
+		}
+		// The source may already be injected into a value that this
+		// destination can point to.
+		key.dstType = dstValue.Type().Elem()
+		if p, ok := in.shared[key]; ok {
+			return p, true
+		}
+	case reflect.Interface:
+		// Values that are deep copied into interfaces are injected into
+		// values of the source type first.
+		key.dstType = key.srcType
+		if p, ok := in.shared[key]; ok && key.srcType.AssignableTo(dstValue.Type()) {
+			return p.Elem(), true
+		}
+	}
+	return reflect.Value{}, false
+}
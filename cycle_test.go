@@ -0,0 +1,241 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type nodeDTO struct {
+	Name     string
+	Parent   *nodeDTO
+	Children []*nodeDTO
+}
+
+type Node struct {
+	Name     string
+	Parent   *Node
+	Children []*Node
+}
+
+type Tree struct {
+	Name     string
+	Children []Tree
+}
+
+type Graph map[string]Graph
+
+func newNodeDTOTree() *nodeDTO {
+	root := &nodeDTO{Name: "root"}
+	root.Children = []*nodeDTO{
+		{Name: "a", Parent: root},
+		{Name: "b", Parent: root},
+	}
+	return root
+}
+
+func TestInjectPointerToDifferentType(t *testing.T) {
+	s := &nodeDTO{
+		Name: "root",
+		Children: []*nodeDTO{
+			{Name: "a"},
+		},
+	}
+	var d Node
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Name != "root" || len(d.Children) != 1 || d.Children[0].Name != "a" {
+		t.Errorf("unexpected destination %#v", d)
+	}
+}
+
+func TestInjectValueToPointer(t *testing.T) {
+	s := map[string]interface{}{
+		"Timeout": 1.5,
+	}
+	var d struct {
+		Timeout *float64
+	}
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Timeout == nil || *d.Timeout != 1.5 {
+		t.Errorf("unexpected destination %#v", d)
+	}
+}
+
+func TestCycleError(t *testing.T) {
+	tree := map[string]interface{}{
+		"Name": "root",
+	}
+	tree["Children"] = []interface{}{
+		map[string]interface{}{"Name": "leaf"},
+		tree,
+	}
+
+	graph := map[string]interface{}{}
+	graph["self"] = graph
+
+	for _, tc := range []struct {
+		name string
+		src  interface{}
+		dst  interface{}
+		path string
+		ref  string
+	}{
+		{
+			name: "pointer graph",
+			src:  newNodeDTOTree(),
+			dst:  new(Node),
+			path: "Children[0].Parent",
+			ref:  "",
+		},
+		{
+			name: "map in slice",
+			src:  tree,
+			dst:  new(Tree),
+			path: "Children[1]",
+			ref:  "",
+		},
+		{
+			name: "map in map",
+			src:  graph,
+			dst:  new(Graph),
+			path: "[self]",
+			ref:  "",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Inject(tc.src, tc.dst)
+			var cerr *CycleError
+			if !errors.As(err, &cerr) {
+				t.Fatalf("Expected CycleError, but got %#v", err)
+			}
+			if cerr.Path != tc.path {
+				t.Errorf("got path %q, want %q", cerr.Path, tc.path)
+			}
+			if cerr.Ref != tc.ref {
+				t.Errorf("got ref %q, want %q", cerr.Ref, tc.ref)
+			}
+		})
+	}
+}
+
+func TestSharedReferencesCycle(t *testing.T) {
+	in := NewInjector(WithSharedReferences())
+
+	var d Node
+	if err := in.Inject(newNodeDTOTree(), &d); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Children) != 2 {
+		t.Fatalf("got %v children, want 2", len(d.Children))
+	}
+	for _, c := range d.Children {
+		if c.Parent != &d {
+			t.Errorf("child %q parent %p is not the root %p", c.Name, c.Parent, &d)
+		}
+	}
+
+	graph := map[string]interface{}{}
+	graph["self"] = graph
+	var g Graph
+	if err := in.Inject(graph, &g); err != nil {
+		t.Fatal(err)
+	}
+	if reflect.ValueOf(g["self"]).Pointer() != reflect.ValueOf(g).Pointer() {
+		t.Error("map does not contain itself")
+	}
+}
+
+func TestSharedReferencesCycleInterface(t *testing.T) {
+	graph := map[string]any{}
+	graph["self"] = graph
+	c, err := Clone[any](graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := c.(map[string]any)
+	if !ok {
+		t.Fatalf("got %T, want %T", c, graph)
+	}
+	if reflect.ValueOf(m["self"]).Pointer() != reflect.ValueOf(m).Pointer() {
+		t.Error("map does not contain itself")
+	}
+	if reflect.ValueOf(m).Pointer() == reflect.ValueOf(graph).Pointer() {
+		t.Error("map is not copied")
+	}
+
+	p := &nodeDTO{Name: "shared"}
+	s := []any{p, p}
+	d, err := Clone[any](s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := d.([]any)
+	if l[0] != l[1] || l[0] == any(p) {
+		t.Errorf("got %#v, want a shared copy of %#v", l, p)
+	}
+}
+
+func TestSharedReferencesCycleByValue(t *testing.T) {
+	tree := map[string]interface{}{
+		"Name": "root",
+	}
+	tree["Children"] = []interface{}{tree}
+	var d Tree
+	err := NewInjector(WithSharedReferences()).Inject(tree, &d)
+	var cerr *CycleError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("Expected CycleError, but got %#v", err)
+	}
+}
+
+func TestSharedReferences(t *testing.T) {
+	shared := &nodeDTO{Name: "shared"}
+	s := struct {
+		First  *nodeDTO
+		Second *nodeDTO
+		Tags   []string
+		More   []string
+	}{
+		First:  shared,
+		Second: shared,
+		Tags:   []string{"a", "b"},
+	}
+	s.More = s.Tags
+	type dst struct {
+		First  *Node
+		Second *Node
+		Tags   []string
+		More   []string
+	}
+
+	var d dst
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.First == d.Second {
+		t.Error("pointers are shared without preserving shared references")
+	}
+
+	d = dst{}
+	if err := NewInjector(WithSharedReferences()).Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.First != d.Second {
+		t.Error("pointers are not shared")
+	}
+	if d.First.Name != "shared" {
+		t.Errorf("got name %q, want %q", d.First.Name, "shared")
+	}
+	if &d.Tags[0] != &d.More[0] {
+		t.Error("slices are not shared")
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import "reflect"
+
+// WithDeepCopy configures Injector to copy all values referenced by pointers,
+// slices, maps, arrays and interfaces from the source, so that the
+// destination does not share any of them with the source. Nil slices and maps
+// remain nil, and structs without exported fields, like time.Time, are copied
+// as values. Unexported fields of other structs, channels and functions are
+// not copied.
+func WithDeepCopy() Option {
+	return func(in *Injector) {
+		in.deepCopy = true
+	}
+}
+
+// DeepCopy injects the source into the destination without sharing any
+// pointers, slices, maps or arrays between them. References that are shared
+// in the source are shared in the destination, too, and cycles are
+// preserved. Struct fields with the "-" key name are skipped and fields with
+// the shallow tag option are assigned without copying.
+func DeepCopy(src, dst interface{}) error {
+	return NewInjector(WithDeepCopy(), WithSharedReferences()).Inject(src, dst)
+}
+
+// Clone returns a deep copy of the value in the same way as DeepCopy.
+func Clone[T any](v T) (T, error) {
+	var c T
+	err := DeepCopy(v, &c)
+	return c, err
+}
+
+// copier returns an Injector that deep copies values with the same tag key,
+// without hooks, converters and validation.
+func (in *Injector) copier() *Injector {
+	return NewInjector(
+		WithTagKey(in.tagKey),
+		WithDeepCopy(),
+		WithSharedReferences(),
+	)
+}
+
+// copying returns true if the source value, or the value that it references,
+// is deep copied into the destination of the same type. Such values are
+// copied as they are, without hooks, validation and struct tag options other
+// than the "-" key name and the shallow option.
+func (in *injection) copying(srcValue reflect.Value, dstType reflect.Type) bool {
+	if !in.deepCopy {
+		return false
+	}
+	for srcValue.IsValid() && srcValue.Type() != dstType {
+		if srcValue.Kind() != reflect.Interface && srcValue.Kind() != reflect.Ptr || srcValue.IsNil() {
+			return false
+		}
+		srcValue = srcValue.Elem()
+	}
+	return srcValue.IsValid()
+}
+
+// copyFields deep copies exported fields of the source struct into the
+// destination struct of the same type by their indexes. Fields with the "-"
+// key name are skipped and fields with the shallow tag option are assigned
+// without copying.
+func (in *injection) copyFields(srcValue, dstValue reflect.Value, path string) error {
+	t := dstValue.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !isExported(f) || keyNameFromTag(f.Tag, in.tagKey) == "-" {
+			continue
+		}
+		if tagContains(f.Tag, in.tagKey, "shallow") {
+			dstValue.Field(i).Set(srcValue.Field(i))
+			in.record(srcValue.Field(i), dstValue.Field(i), fieldPath(path, f.Name))
+			continue
+		}
+		if err := in.inject(srcValue.Field(i), dstValue.Field(i).Addr(), fieldPath(path, f.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyDeep copies values that can not be injected field by field or element
+// by element when values are deep copied. It returns false for all other
+// values.
+func (in *injection) copyDeep(srcValue, dstValue reflect.Value, path string) (handled bool, err error) {
+	if !in.deepCopy {
+		return false, nil
+	}
+	if dstValue.Kind() == reflect.Interface {
+		if !srcValue.Type().AssignableTo(dstValue.Type()) {
+			return false, nil
+		}
+		v := reflect.New(srcValue.Type())
+		if err := in.inject(srcValue, v, path); err != nil {
+			return true, err
+		}
+		dstValue.Set(v.Elem())
+		return true, nil
+	}
+	v := reflect.Indirect(srcValue)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		if v.IsNil() && dstValue.Kind() == v.Kind() {
+			dstValue.Set(reflect.Zero(dstValue.Type()))
+			return true, nil
+		}
+	case reflect.Struct:
+		if v.Type() == dstValue.Type() && !hasExportedFields(v.Type()) {
+			dstValue.Set(v)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if isExported(t.Field(i)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Document struct {
+	Title    string
+	Tags     []string
+	Meta     map[string]interface{}
+	Author   *Author
+	Scores   [2]*int
+	Extra    interface{}
+	Nil      []int
+	Created  time.Time
+	Cache    map[string]string `taint:",shallow"`
+	Internal string            `taint:"-"`
+	Authors  []Author
+}
+
+type Author struct {
+	Name   string
+	Emails []string
+}
+
+func newDocument() Document {
+	one, two := 1, 2
+	return Document{
+		Title: "Deep",
+		Tags:  []string{"a", "b"},
+		Meta: map[string]interface{}{
+			"nested": map[string]interface{}{
+				"list": []interface{}{1, "two"},
+			},
+		},
+		Author: &Author{
+			Name:   "alice",
+			Emails: []string{"alice@example.com"},
+		},
+		Scores:  [2]*int{&one, &two},
+		Extra:   &Author{Name: "bob"},
+		Created: time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC),
+		Cache: map[string]string{
+			"k": "v",
+		},
+		Authors: []Author{
+			{Name: "carol", Emails: []string{"carol@example.com"}},
+		},
+	}
+}
+
+func TestClone(t *testing.T) {
+	s := newDocument()
+	s.Internal = "internal"
+	c, err := Clone(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := newDocument()
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("%T clone %#v is not %#v", c, c, expected)
+	}
+
+	c.Tags[0] = "changed"
+	c.Meta["nested"].(map[string]interface{})["list"].([]interface{})[0] = 100
+	c.Author.Name = "changed"
+	c.Author.Emails[0] = "changed"
+	*c.Scores[0] = 100
+	c.Extra.(*Author).Name = "changed"
+	c.Authors[0].Emails[0] = "changed"
+	expected.Internal = "internal"
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("%T source %#v is changed to %#v", s, expected, s)
+	}
+
+	c.Cache["k"] = "changed"
+	if s.Cache["k"] != "changed" {
+		t.Errorf("shallow field is copied")
+	}
+}
+
+func TestCloneCycle(t *testing.T) {
+	root := &Node{Name: "root"}
+	child := &Node{Name: "child", Parent: root}
+	root.Children = []*Node{child, child}
+
+	c, err := Clone(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c == root || c.Children[0] == child {
+		t.Fatal("clone shares nodes with source")
+	}
+	if c.Children[0] != c.Children[1] {
+		t.Error("shared child is not preserved")
+	}
+	if c.Children[0].Parent != c {
+		t.Error("cycle is not preserved")
+	}
+	if c.Name != "root" || c.Children[0].Name != "child" {
+		t.Errorf("unexpected clone %#v", c)
+	}
+}
+
+func TestCloneInterface(t *testing.T) {
+	s := []interface{}{
+		map[string]interface{}{"a": []int{1}},
+		[]int{2},
+	}
+	c, err := Clone[interface{}](s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c, s) {
+		t.Errorf("%T clone %#v is not %#v", c, c, s)
+	}
+	c.([]interface{})[0].(map[string]interface{})["a"].([]int)[0] = 100
+	c.([]interface{})[1].([]int)[0] = 100
+	if s[0].(map[string]interface{})["a"].([]int)[0] != 1 || s[1].([]int)[0] != 2 {
+		t.Errorf("source is changed to %#v", s)
+	}
+}
+
+func TestDeepCopyArray(t *testing.T) {
+	one := 1
+	s := [1]*int{&one}
+	var d [1]*int
+	if err := DeepCopy(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d[0] == s[0] || *d[0] != 1 {
+		t.Errorf("%T destination %#v is not a copy of %#v", d, d, s)
+	}
+}
+
+type cloneAddress struct {
+	City string `taint:"city,upper"`
+}
+
+type cloneDTO struct {
+	Street   string       `taint:"from=Addr.Street"`
+	Addr     cloneAddress `taint:"prefix=Addr"`
+	Name     string       `taint:"name,trim,upper"`
+	Port     int          `taint:"port,min=1,required"`
+	Internal string       `taint:"-"`
+	hooks    *int
+}
+
+func (d *cloneDTO) BeforeInject(interface{}) error {
+	return errors.New("before inject called")
+}
+
+func (d cloneDTO) Validate() error {
+	return errors.New("validate called")
+}
+
+func TestCloneIgnoresTagRules(t *testing.T) {
+	src := cloneDTO{
+		Street:   "Main",
+		Addr:     cloneAddress{City: "Paris"},
+		Name:     " bob ",
+		Internal: "skip",
+	}
+	c, err := Clone(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := src
+	expected.Internal = ""
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", c, c, expected)
+	}
+
+	var d cloneDTO
+	in := NewInjector(WithDeepCopy(), WithFieldHook(func(context.Context, string, interface{}, interface{}) (interface{}, error) {
+		return nil, errors.New("field hook called")
+	}))
+	if err := in.Inject(&src, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+}
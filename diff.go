@@ -0,0 +1,402 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ChangeType is the type of a Change.
+type ChangeType int
+
+// Types of changes reported by Diff.
+const (
+	Added ChangeType = iota + 1
+	Removed
+	Modified
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	}
+	return "unknown"
+}
+
+// Change is a difference between two values at the path. Old is nil for
+// added values and New is nil for removed values.
+type Change struct {
+	Type ChangeType
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+func (c Change) String() string {
+	path := c.Path
+	if path == "" {
+		path = "root"
+	}
+	switch c.Type {
+	case Added:
+		return fmt.Sprintf("%s %s: %v", c.Type, path, c.New)
+	case Removed:
+		return fmt.Sprintf("%s %s: %v", c.Type, path, c.Old)
+	}
+	return fmt.Sprintf("%s %s: %v -> %v", c.Type, path, c.Old, c.New)
+}
+
+// Diff returns changes that turn the value a into the value b. It is the
+// same as calling the Diff method of Injector with default options.
+func Diff(a, b interface{}) ([]Change, error) {
+	return NewInjector().Diff(a, b)
+}
+
+// Diff returns changes that turn the value a into the value b. Struct fields
+// and map entries are matched by their key names in the same way as Inject
+// does, so that a struct can be compared to a map source. Slices and arrays
+// are compared by indexes, or by the value of the element struct field with
+// the key tag option, and pointers and interfaces are compared by values
+// that they reference. Struct fields that are unexported or skipped by the
+// struct tag are not compared. Changes are ordered by struct fields, sorted
+// map keys and slice elements. Values of struct fields with the secret tag
+// option are replaced by the secret mask in changes.
+func (in *Injector) Diff(a, b interface{}) ([]Change, error) {
+	d := &differ{
+		in:       in,
+		visiting: make(map[[2]visit]struct{}),
+	}
+	if err := d.diff(reflect.ValueOf(a), reflect.ValueOf(b), ""); err != nil {
+		return nil, err
+	}
+	return d.changes, nil
+}
+
+// differ holds the state of a single Diff call.
+type differ struct {
+	in      *Injector
+	changes []Change
+	// masked is true while values of a struct field with the secret tag
+	// option are compared.
+	masked bool
+	// visiting holds pairs of pointers, maps and slices that are currently
+	// compared, to stop at cycles.
+	visiting map[[2]visit]struct{}
+}
+
+func (d *differ) add(t ChangeType, path string, a, b reflect.Value) {
+	d.changes = append(d.changes, Change{
+		Type: t,
+		Path: path,
+		Old:  d.value(a),
+		New:  d.value(b),
+	})
+}
+
+// value returns the value for the change, with values of struct fields with
+// the secret tag option replaced by the mask.
+func (d *differ) value(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	if d.masked {
+		return d.in.secretMask
+	}
+	if d.in.containsSecrets(v) {
+		c, err := d.in.redactedCopy(v)
+		if err != nil {
+			return d.in.secretMask
+		}
+		return c.Interface()
+	}
+	return v.Interface()
+}
+
+func valueInterface(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// diffIndirect returns the value that the pointer or the interface
+// references, or an invalid value if it is nil.
+func diffIndirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// enter marks the pair of references as being compared. It returns false if
+// they are already compared, as they are in a cycle.
+func (d *differ) enter(a, b reflect.Value) (leave func(), ok bool) {
+	aKey, aOK := newVisit(a, nil)
+	bKey, bOK := newVisit(b, nil)
+	if !aOK || !bOK {
+		return func() {}, true
+	}
+	key := [2]visit{aKey, bKey}
+	if _, ok := d.visiting[key]; ok {
+		return nil, false
+	}
+	d.visiting[key] = struct{}{}
+	return func() { delete(d.visiting, key) }, true
+}
+
+func (d *differ) diff(a, b reflect.Value, path string) error {
+	if a.Kind() == reflect.Ptr && b.Kind() == reflect.Ptr {
+		leave, ok := d.enter(a, b)
+		if !ok {
+			return nil
+		}
+		defer leave()
+	}
+	// Maps and slices are tracked after pointers and interfaces are
+	// dereferenced, as they may be reached through different interfaces.
+	a, b = diffIndirect(a), diffIndirect(b)
+	leave, ok := d.enter(a, b)
+	if !ok {
+		return nil
+	}
+	defer leave()
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() || b.IsValid() {
+			d.add(Modified, path, a, b)
+		}
+		return nil
+	}
+	switch {
+	case isEntries(a) && isEntries(b):
+		return d.diffEntries(a, b, path)
+	case isList(a) && isList(b):
+		if key, ok := d.listKey(a, b); ok {
+			return d.diffKeyed(a, b, key, path)
+		}
+		return d.diffIndexed(a, b, path)
+	}
+	if !equalValues(a, b) {
+		d.add(Modified, path, a, b)
+	}
+	return nil
+}
+
+// isEntries returns true for maps and structs with exported fields.
+func isEntries(v reflect.Value) bool {
+	return v.Kind() == reflect.Map || v.Kind() == reflect.Struct && hasExportedFields(v.Type())
+}
+
+func isList(v reflect.Value) bool {
+	return v.Kind() == reflect.Slice || v.Kind() == reflect.Array
+}
+
+// entry is a struct field or a map entry with its path.
+type entry struct {
+	name   string
+	path   string
+	value  reflect.Value
+	secret bool
+}
+
+// entries returns struct fields that are not skipped by the struct tag
+// under their key names, or map entries ordered by their keys.
+func (d *differ) entries(v reflect.Value, path string) (l []entry) {
+	if v.Kind() == reflect.Struct {
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !isExported(f) {
+				continue
+			}
+			keyName := keyNameFromTag(f.Tag, d.in.tagKey)
+			if keyName == "-" {
+				continue
+			}
+			if keyName == "" {
+				keyName = f.Name
+			}
+			l = append(l, entry{
+				name:   keyName,
+				path:   fieldPath(path, keyName),
+				value:  v.Field(i),
+				secret: isSecret(f, d.in.tagKey),
+			})
+		}
+		return l
+	}
+	for _, k := range v.MapKeys() {
+		l = append(l, entry{
+			name:  fmt.Sprint(k.Interface()),
+			path:  keyPath(path, k),
+			value: v.MapIndex(k),
+		})
+	}
+	sort.Slice(l, func(i, j int) bool {
+		return l[i].name < l[j].name
+	})
+	return l
+}
+
+func (d *differ) diffEntries(a, b reflect.Value, path string) error {
+	aEntries := d.entries(a, path)
+	bEntries := d.entries(b, path)
+	bIndex := make(map[string]int, len(bEntries))
+	for i, e := range bEntries {
+		bIndex[e.name] = i
+	}
+	seen := make(map[string]struct{}, len(aEntries))
+	for _, e := range aEntries {
+		seen[e.name] = struct{}{}
+		i, ok := bIndex[e.name]
+		if !ok {
+			restore := d.mask(e.secret)
+			d.add(Removed, e.path, e.value, reflect.Value{})
+			restore()
+			continue
+		}
+		restore := d.mask(e.secret || bEntries[i].secret)
+		err := d.diff(e.value, bEntries[i].value, e.path)
+		restore()
+		if err != nil {
+			return err
+		}
+	}
+	for _, e := range bEntries {
+		if _, ok := seen[e.name]; !ok {
+			restore := d.mask(e.secret)
+			d.add(Added, e.path, reflect.Value{}, e.value)
+			restore()
+		}
+	}
+	return nil
+}
+
+// mask masks values of changes if secret is true, until the returned
+// function is called.
+func (d *differ) mask(secret bool) (restore func()) {
+	masked := d.masked
+	d.masked = masked || secret
+	return func() { d.masked = masked }
+}
+
+func (d *differ) diffIndexed(a, b reflect.Value, path string) error {
+	for i := 0; i < a.Len() || i < b.Len(); i++ {
+		switch {
+		case i >= b.Len():
+			d.add(Removed, indexPath(path, i), a.Index(i), reflect.Value{})
+		case i >= a.Len():
+			d.add(Added, indexPath(path, i), reflect.Value{}, b.Index(i))
+		default:
+			if err := d.diff(a.Index(i), b.Index(i), indexPath(path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listKey returns the name of the struct field with the key tag option of
+// elements of both lists.
+func (d *differ) listKey(a, b reflect.Value) (string, bool) {
+	aKey, ok := d.elemKey(a.Type().Elem())
+	if !ok {
+		return "", false
+	}
+	bKey, ok := d.elemKey(b.Type().Elem())
+	return aKey, ok && aKey == bKey
+}
+
+func (d *differ) elemKey(t reflect.Type) (string, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return "", false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if isExported(f) && tagContains(f.Tag, d.in.tagKey, "key") {
+			return f.Name, true
+		}
+	}
+	return "", false
+}
+
+// keyed returns elements of the list by the string value of their key
+// field, in the order of the list.
+func (d *differ) keyed(v reflect.Value, key, path string) (keys []string, elems map[string]reflect.Value, err error) {
+	elems = make(map[string]reflect.Value, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		e := v.Index(i)
+		k := diffIndirect(e)
+		if !k.IsValid() {
+			continue
+		}
+		s := fmt.Sprint(k.FieldByName(key).Interface())
+		if _, ok := elems[s]; ok {
+			return nil, nil, &KeyCollisionError{
+				Path: indexPath(path, i),
+				Key:  s,
+			}
+		}
+		keys = append(keys, s)
+		elems[s] = e
+	}
+	return keys, elems, nil
+}
+
+func (d *differ) diffKeyed(a, b reflect.Value, key, path string) error {
+	aKeys, aElems, err := d.keyed(a, key, path)
+	if err != nil {
+		return err
+	}
+	bKeys, bElems, err := d.keyed(b, key, path)
+	if err != nil {
+		return err
+	}
+	for _, k := range aKeys {
+		p := keyPath(path, reflect.ValueOf(k))
+		be, ok := bElems[k]
+		if !ok {
+			d.add(Removed, p, aElems[k], reflect.Value{})
+			continue
+		}
+		if err := d.diff(aElems[k], be, p); err != nil {
+			return err
+		}
+	}
+	for _, k := range bKeys {
+		if _, ok := aElems[k]; !ok {
+			d.add(Added, keyPath(path, reflect.ValueOf(k)), reflect.Value{}, bElems[k])
+		}
+	}
+	return nil
+}
+
+// equalValues returns true if values are deeply equal, or if the value b
+// converted to the type of the value a is equal to it.
+func equalValues(a, b reflect.Value) bool {
+	if !a.CanInterface() || !b.CanInterface() {
+		return false
+	}
+	if a.Type() == b.Type() {
+		return reflect.DeepEqual(a.Interface(), b.Interface())
+	}
+	c := reflect.New(a.Type()).Elem()
+	if !convertBasic(b, c) {
+		return false
+	}
+	return reflect.DeepEqual(a.Interface(), c.Interface())
+}
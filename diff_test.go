@@ -0,0 +1,154 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type diffServer struct {
+	Name string `taint:"name,key"`
+	Port int    `taint:"port"`
+}
+
+type diffConfig struct {
+	Name     string            `taint:"name"`
+	Timeout  *int              `taint:"timeout"`
+	Labels   map[string]string `taint:"labels"`
+	Hosts    []string          `taint:"hosts"`
+	Servers  []diffServer      `taint:"servers"`
+	Internal string            `taint:"-"`
+}
+
+func TestDiff(t *testing.T) {
+	ten, twenty := 10, 20
+	a := diffConfig{
+		Name:    "app",
+		Timeout: &ten,
+		Labels: map[string]string{
+			"env":  "dev",
+			"team": "core",
+		},
+		Hosts: []string{"a", "b", "c"},
+		Servers: []diffServer{
+			{Name: "web1", Port: 80},
+			{Name: "web2", Port: 80},
+		},
+		Internal: "a",
+	}
+	b := diffConfig{
+		Name:    "app",
+		Timeout: &twenty,
+		Labels: map[string]string{
+			"env":    "prod",
+			"region": "eu",
+		},
+		Hosts: []string{"a", "x"},
+		Servers: []diffServer{
+			{Name: "web2", Port: 8080},
+			{Name: "web3", Port: 80},
+		},
+		Internal: "b",
+	}
+	got, err := Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Change{
+		{Type: Modified, Path: "timeout", Old: 10, New: 20},
+		{Type: Modified, Path: "labels[env]", Old: "dev", New: "prod"},
+		{Type: Removed, Path: "labels[team]", Old: "core"},
+		{Type: Added, Path: "labels[region]", New: "eu"},
+		{Type: Modified, Path: "hosts[1]", Old: "b", New: "x"},
+		{Type: Removed, Path: "hosts[2]", Old: "c"},
+		{Type: Removed, Path: "servers[web1]", Old: diffServer{Name: "web1", Port: 80}},
+		{Type: Modified, Path: "servers[web2].port", Old: 80, New: 8080},
+		{Type: Added, Path: "servers[web3]", New: diffServer{Name: "web3", Port: 80}},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got changes %v, want %v", got, expected)
+	}
+}
+
+func TestDiffMapSource(t *testing.T) {
+	a := diffConfig{
+		Name:  "app",
+		Hosts: []string{"a"},
+	}
+	b := map[string]interface{}{
+		"name":    "app",
+		"timeout": 5,
+		"hosts":   []interface{}{"a", "b"},
+	}
+	got, err := Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Change{
+		{Type: Modified, Path: "timeout", New: 5},
+		{Type: Removed, Path: "labels", Old: map[string]string(nil)},
+		{Type: Added, Path: "hosts[1]", New: "b"},
+		{Type: Removed, Path: "servers", Old: []diffServer(nil)},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got changes %v, want %v", got, expected)
+	}
+}
+
+func TestDiffEqual(t *testing.T) {
+	root := &Node{Name: "root"}
+	root.Children = []*Node{{Name: "child", Parent: root}}
+	other := &Node{Name: "root"}
+	other.Children = []*Node{{Name: "child", Parent: other}}
+	got, err := Diff(root, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("got changes %v, want none", got)
+	}
+}
+
+func TestDiffSelfReferences(t *testing.T) {
+	m := map[string]interface{}{"name": "a"}
+	m["self"] = m
+	l := []interface{}{"a", nil}
+	l[1] = l
+	for _, v := range []interface{}{m, l} {
+		got, err := Diff(v, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 0 {
+			t.Errorf("got changes %v, want none", got)
+		}
+	}
+
+	n := map[string]interface{}{"name": "b"}
+	n["self"] = n
+	got, err := Diff(m, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Change{{Type: Modified, Path: "[name]", Old: "a", New: "b"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got changes %v, want %v", got, expected)
+	}
+}
+
+func TestDiffKeyCollision(t *testing.T) {
+	a := []diffServer{{Name: "web1"}, {Name: "web1"}}
+	_, err := Diff(a, a)
+	var kerr *KeyCollisionError
+	if !errors.As(err, &kerr) {
+		t.Fatalf("Expected KeyCollisionError, but got %#v", err)
+	}
+	if kerr.Path != "[1]" {
+		t.Errorf("Expected KeyCollisionError Path [1], but got %#v", kerr.Path)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import "reflect"
+
+var (
+	beforeInjectorType = reflect.TypeOf((*BeforeInjector)(nil)).Elem()
+	afterInjectorType  = reflect.TypeOf((*AfterInjector)(nil)).Elem()
+)
+
+// direct returns true if source values of the type are injected into
+// destinations of the same type only by assignment, so that they can be set
+// in place, without allocations of the injection.
+func (in *injection) direct(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128, reflect.String:
+	default:
+		return false
+	}
+	if in.recording() || in.maxDepth > 0 && in.depth >= in.maxDepth {
+		return false
+	}
+	if _, ok := in.converters[t]; ok {
+		return false
+	}
+	if in.registeredEnum(t) != nil {
+		return false
+	}
+	if t.Implements(sourceType) {
+		return false
+	}
+	p := reflect.PointerTo(t)
+	return !p.Implements(beforeInjectorType) &&
+		!p.Implements(afterInjectorType) &&
+		!p.Implements(textUnmarshalerType)
+}
+
+// setDirect sets the destination to the source value in place if they are
+// of the same type that is injected directly. It returns false if the
+// source value must be injected.
+func (in *injection) setDirect(srcValue, dstValue reflect.Value) bool {
+	if srcValue.Kind() == reflect.Interface {
+		srcValue = srcValue.Elem()
+	}
+	if !srcValue.IsValid() || !srcValue.CanInterface() || srcValue.Type() != dstValue.Type() || !in.direct(dstValue.Type()) {
+		return false
+	}
+	dstValue.Set(srcValue)
+	return true
+}
+
+// copyMap sets entries of the source map to the new destination map in
+// place if both keys and values are of the same types that are injected
+// directly. It returns false if entries must be injected.
+func (in *injection) copyMap(srcValue, dstValue reflect.Value, path string) (handled bool, err error) {
+	srcType, dstType := srcValue.Type(), dstValue.Type()
+	if srcType.Key() != dstType.Key() || srcType.Elem() != dstType.Elem() ||
+		!in.direct(dstType.Key()) || !in.direct(dstType.Elem()) {
+		return false, nil
+	}
+	key := reflect.New(dstType.Key()).Elem()
+	value := reflect.New(dstType.Elem()).Elem()
+	iter := srcValue.MapRange()
+	for iter.Next() {
+		key.SetIterKey(iter)
+		value.SetIterValue(iter)
+		dstValue.SetMapIndex(key, value)
+		if err := in.checkContext(path); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
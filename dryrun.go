@@ -0,0 +1,122 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"context"
+	"reflect"
+)
+
+// Assignment is a value that would be set at the path of the destination by
+// the injection. Src is the source value and Dst is the value converted to
+// the destination type.
+type Assignment struct {
+	Path string
+	Src  interface{}
+	Dst  interface{}
+}
+
+// DryRun returns assignments that Inject would make to the destination
+// without changing it. It is the same as calling the DryRun method of
+// Injector with default options.
+func DryRun(src, dst interface{}) ([]Assignment, error) {
+	return NewInjector().DryRun(src, dst)
+}
+
+// DryRun returns assignments that Inject would make to the destination,
+// without changing it. The source is injected into a deep copy of the
+// destination, so hooks, validators and converters are called as with
+// Inject. If the injection fails, assignments that are made before the
+// failure are returned with the error.
+func (in *Injector) DryRun(src, dst interface{}) ([]Assignment, error) {
+	dstValue := reflect.ValueOf(dst)
+	if !dstValue.IsValid() {
+		return nil, &InvalidInjectError{}
+	}
+	if dstValue.Kind() != reflect.Ptr || dstValue.IsNil() {
+		return nil, &InvalidInjectError{dstValue.Type()}
+	}
+	scratch := reflect.New(dstValue.Type().Elem())
+	if err := in.copier().Inject(dstValue.Interface(), scratch.Interface()); err != nil {
+		return nil, err
+	}
+	i := in.newInjection(context.Background())
+	defer i.release()
+	i.dryRun = true
+	err := i.inject(reflect.ValueOf(src), scratch, "")
+	return i.assignments, err
+}
+
+// recording returns true if assignments are recorded for DryRun or for the
+// Result.
+func (in *injection) recording() bool {
+	return in.dryRun || in.result != nil
+}
+
+// record records the assignment of the source value to the destination for
+// DryRun and the path of the destination in the Result. Only values that are
+// not injected into struct, map, slice, array and pointer destinations are
+// recorded, except for strings and numbers, so that every assignment is
+// recorded once. Nil pointers and empty maps and slices are recorded, as they
+// have no values to record.
+func (in *injection) record(srcValue, dstValue reflect.Value, path string) {
+	if !in.recording() || in.injectingKey {
+		return
+	}
+	switch dstValue.Kind() {
+	case reflect.Ptr:
+		if !dstValue.IsNil() {
+			// The referenced value is recorded at the same path.
+			return
+		}
+	case reflect.Map, reflect.Slice:
+		if dstValue.Len() == 0 {
+			break
+		}
+		fallthrough
+	case reflect.Struct, reflect.Array:
+		v := srcValue
+		if v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+			reflect.Float32, reflect.Float64:
+		default:
+			in.unrecord(path)
+			return
+		}
+	}
+	if in.dryRun {
+		in.assignments = append(in.assignments, Assignment{
+			Path: path,
+			Src:  valueInterface(srcValue),
+			Dst:  valueInterface(dstValue),
+		})
+	}
+	if in.result != nil {
+		in.result.set(path, in.label)
+	}
+}
+
+// unrecord removes the path of the destination that has values recorded
+// under it from the Result, if it was recorded as nil or empty by an earlier
+// injection.
+func (in *injection) unrecord(path string) {
+	if in.result != nil {
+		in.result.unset(path)
+	}
+}
+
+// injectKey injects the source map key into the destination map key without
+// recording it as an assignment.
+func (in *injection) injectKey(srcKey, dstKey reflect.Value, path string) error {
+	injectingKey := in.injectingKey
+	in.injectingKey = true
+	defer func() { in.injectingKey = injectingKey }()
+	return in.inject(srcKey, dstKey, path)
+}
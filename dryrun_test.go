@@ -0,0 +1,157 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type dryRunSettings struct {
+	Name    string            `taint:"name"`
+	Port    int               `taint:"port"`
+	Timeout *float64          `taint:"timeout"`
+	Hosts   []string          `taint:"hosts"`
+	Labels  map[string]string `taint:"labels"`
+	Limits  struct {
+		Max int `taint:"max,max=100"`
+	} `taint:"limits"`
+}
+
+func TestDryRun(t *testing.T) {
+	d := dryRunSettings{
+		Name: "app",
+		Port: 80,
+	}
+	d.Limits.Max = 10
+	original := d
+
+	src := map[string]interface{}{
+		"port":    "8080",
+		"timeout": 1.5,
+		"hosts":   []interface{}{"a", "b"},
+		"labels":  map[string]interface{}{"env": "prod"},
+		"limits":  map[string]interface{}{"max": 50},
+	}
+	got, err := NewInjector(WithValueConversion()).DryRun(src, &d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Assignment{
+		{Path: "Port", Src: "8080", Dst: 8080},
+		{Path: "Timeout", Src: 1.5, Dst: 1.5},
+		{Path: "Hosts[0]", Src: "a", Dst: "a"},
+		{Path: "Hosts[1]", Src: "b", Dst: "b"},
+		{Path: "Labels[env]", Src: "prod", Dst: "prod"},
+		{Path: "Limits.Max", Src: 50, Dst: 50},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got assignments %#v, want %#v", got, expected)
+	}
+	if !reflect.DeepEqual(d, original) {
+		t.Errorf("%T destination %#v is changed from %#v", d, d, original)
+	}
+}
+
+func TestDryRunError(t *testing.T) {
+	var d dryRunSettings
+	src := map[string]interface{}{
+		"name":   "app",
+		"limits": map[string]interface{}{"max": 500},
+	}
+	got, err := DryRun(src, &d)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected ValidationError, but got %#v", err)
+	}
+	expected := []Assignment{
+		{Path: "Name", Src: "app", Dst: "app"},
+		{Path: "Limits.Max", Src: 500, Dst: 500},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got assignments %#v, want %#v", got, expected)
+	}
+	if d.Name != "" {
+		t.Errorf("%T destination %#v is changed", d, d)
+	}
+}
+
+func TestDryRunInvalid(t *testing.T) {
+	var d dryRunSettings
+	_, err := DryRun(nil, d)
+	var ierr *InvalidInjectError
+	if !errors.As(err, &ierr) {
+		t.Fatalf("Expected InvalidInjectError, but got %#v", err)
+	}
+}
+
+type dryRunServer struct {
+	Name string `cfg:"name"`
+	Port int    `cfg:"port,min=1" taint:"-"`
+}
+
+func (s dryRunServer) Validate() error {
+	if s.Port == 0 {
+		return errors.New("port is not set")
+	}
+	return nil
+}
+
+func TestDryRunTagKey(t *testing.T) {
+	in := NewInjector(WithTagKey("cfg"))
+
+	d := dryRunServer{Port: 80}
+	got, err := in.DryRun(map[string]interface{}{"name": "app"}, &d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Assignment{
+		{Path: "Name", Src: "app", Dst: "app"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got assignments %#v, want %#v", got, expected)
+	}
+
+	var s struct {
+		Server dryRunServer `cfg:"server"`
+		Port   int          `cfg:"port,min=1"`
+	}
+	s.Server.Port = 80
+	if _, err := in.DryRun(map[string]interface{}{"server": map[string]interface{}{"name": "app"}}, &s); err != nil {
+		t.Errorf("dry run of the destination with unset fields: %v", err)
+	}
+}
+
+func TestDryRunClear(t *testing.T) {
+	type settings struct {
+		Name *string
+		Tags []string
+		M    map[string]int
+	}
+	name := "app"
+	d := settings{
+		Name: &name,
+		Tags: []string{"a"},
+		M:    map[string]int{"a": 1},
+	}
+	got, err := DryRun(map[string]interface{}{
+		"Name": nil,
+		"Tags": []string{},
+		"M":    map[string]int{},
+	}, &d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Assignment{
+		{Path: "Name", Src: nil, Dst: (*string)(nil)},
+		{Path: "Tags", Src: []string{}, Dst: []string{}},
+		{Path: "M", Src: map[string]int{}, Dst: map[string]int{}},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got assignments %#v, want %#v", got, expected)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"context"
+	"iter"
+	"reflect"
+)
+
+// InjectEach injects every value from the sequence into a new value of type
+// T and passes it to the function, one by one, so that sequences of any
+// length can be injected without holding all values in memory. Errors are
+// reported with the index of the value in the path. Iteration stops on the
+// first error from injection or from the function, and that error is
+// returned. Options configure the Injector that is used for all values.
+func InjectEach[T any](src iter.Seq[any], fn func(T) error, opts ...Option) error {
+	in := NewInjector(opts...)
+	i := 0
+	for v := range src {
+		if err := injectElement(context.Background(), in, v, i, fn); err != nil {
+			return err
+		}
+		i++
+	}
+	return nil
+}
+
+// InjectEachChan injects every value received from the channel into a new
+// value of type T and passes it to the function, in the same way as
+// InjectEach, until the channel is closed. If the context is canceled,
+// ContextError is returned.
+func InjectEachChan[T any](ctx context.Context, src <-chan any, fn func(T) error, opts ...Option) error {
+	in := NewInjector(opts...)
+	for i := 0; ; i++ {
+		select {
+		case v, ok := <-src:
+			if !ok {
+				return nil
+			}
+			if err := injectElement(ctx, in, v, i, fn); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return &ContextError{
+				Path: indexPath("", i),
+				Err:  ctx.Err(),
+			}
+		}
+	}
+}
+
+func injectElement[T any](ctx context.Context, in *Injector, v any, i int, fn func(T) error) error {
+	var dst T
+	s := in.newInjection(ctx)
+	err := s.inject(reflect.ValueOf(v), reflect.ValueOf(&dst), indexPath("", i))
+	s.release()
+	if err != nil {
+		return err
+	}
+	return fn(dst)
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+type eachRecord struct {
+	ID   int    `taint:"id"`
+	Name string `taint:"name,nonzero"`
+}
+
+func eachRows(n int) func(yield func(any) bool) {
+	return func(yield func(any) bool) {
+		for i := 0; i < n; i++ {
+			if !yield(map[string]interface{}{"id": i, "name": "row"}) {
+				return
+			}
+		}
+	}
+}
+
+func TestInjectEach(t *testing.T) {
+	var got []eachRecord
+	err := InjectEach(eachRows(3), func(r eachRecord) error {
+		got = append(got, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []eachRecord{{0, "row"}, {1, "row"}, {2, "row"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got records %#v, want %#v", got, expected)
+	}
+}
+
+func TestInjectEachStop(t *testing.T) {
+	errStop := errors.New("stop")
+	var n int
+	err := InjectEach(eachRows(1000), func(r *eachRecord) error {
+		n++
+		if r.ID == 2 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Fatalf("got error %v, want %v", err, errStop)
+	}
+	if n != 3 {
+		t.Errorf("got %v calls, want 3", n)
+	}
+}
+
+func TestInjectEachError(t *testing.T) {
+	src := slices.Values([]any{
+		map[string]interface{}{"id": 1, "name": "a"},
+		map[string]interface{}{"id": 2, "name": ""},
+	})
+	err := InjectEach(src, func(eachRecord) error { return nil })
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected ValidationError, but got %#v", err)
+	}
+	if verr.Path != "[1].Name" {
+		t.Errorf("Expected ValidationError Path [1].Name, but got %#v", verr.Path)
+	}
+}
+
+func TestInjectEachOptions(t *testing.T) {
+	src := slices.Values([]any{
+		map[string]interface{}{"id": 1, "name": "a", "extra": true},
+	})
+	err := InjectEach(src, func(map[string]interface{}) error { return nil }, WithMaxMapEntries(2))
+	var lerr *LimitError
+	if !errors.As(err, &lerr) {
+		t.Fatalf("Expected LimitError, but got %#v", err)
+	}
+}
+
+func TestInjectEachChan(t *testing.T) {
+	ch := make(chan any)
+	go func() {
+		defer close(ch)
+		for v := range eachRows(3) {
+			ch <- v
+		}
+	}()
+	var ids []int
+	err := InjectEachChan(context.Background(), ch, func(r eachRecord) error {
+		ids = append(ids, r.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []int{0, 1, 2}) {
+		t.Errorf("got ids %v", ids)
+	}
+}
+
+func TestInjectEachChanContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan any, 1)
+	ch <- map[string]interface{}{"id": 1, "name": "a"}
+	err := InjectEachChan(ctx, ch, func(eachRecord) error {
+		cancel()
+		return nil
+	})
+	var cerr *ContextError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("Expected ContextError, but got %#v", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, but got %v", err)
+	}
+	if cerr.Path != "[1]" {
+		t.Errorf("Expected ContextError Path [1], but got %#v", cerr.Path)
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// WithCaseInsensitiveEnums configures Injector to look up names of enums
+// registered with RegisterEnum and listed by the enum tag option without
+// regard to case.
+func WithCaseInsensitiveEnums() Option {
+	return func(in *Injector) {
+		in.enumFold = true
+	}
+}
+
+// RegisterEnum registers values of the enum type of the example value,
+// provided as a map from names to values of that type. A string injected
+// into a destination of the enum type is set to the value with the same
+// name, and other sources are converted to the enum type and must be equal
+// to one of the values, otherwise EnumError is returned. When a struct is
+// injected into a map, values of enum types of integer kinds are replaced by
+// their names if the map values can hold strings.
+//
+// The enum tag option in the enum=a|b|c form lists allowed names for a
+// struct field in the same way. Fields of string kinds are set to the listed
+// names and fields of integer kinds to the indexes of the listed names.
+//
+// RegisterEnum panics if the enum type is not of a string or an integer kind,
+// or if values are not a map from strings to values of the enum type.
+func (in *Injector) RegisterEnum(example, values interface{}) {
+	t := reflect.TypeOf(example)
+	if t == nil || !isEnumKind(t.Kind()) {
+		panic("taint: register enum of unsupported type " + typeString(t))
+	}
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String || v.Type().Elem() != t {
+		panic("taint: register enum " + t.String() + " with values of type " + typeString(reflect.TypeOf(values)) + " instead of a map of strings to " + t.String())
+	}
+	e := new(enum)
+	for _, k := range v.MapKeys() {
+		e.names = append(e.names, k.String())
+	}
+	sort.Strings(e.names)
+	for _, name := range e.names {
+		e.values = append(e.values, v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key())))
+	}
+
+	in.enumsMu.Lock()
+	defer in.enumsMu.Unlock()
+
+	if in.enums == nil {
+		in.enums = make(map[reflect.Type]*enum)
+	}
+	in.enums[t] = e
+}
+
+// registeredEnum returns the enum registered for the type, or nil.
+func (in *Injector) registeredEnum(t reflect.Type) *enum {
+	in.enumsMu.RLock()
+	defer in.enumsMu.RUnlock()
+
+	return in.enums[t]
+}
+
+// hasEnums returns true if any enum is registered.
+func (in *Injector) hasEnums() bool {
+	in.enumsMu.RLock()
+	defer in.enumsMu.RUnlock()
+
+	return len(in.enums) > 0
+}
+
+func isEnumKind(k reflect.Kind) bool {
+	return k == reflect.String || isIntKind(k)
+}
+
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// enum holds names and values of an enum, in the same order.
+type enum struct {
+	names  []string
+	values []reflect.Value
+}
+
+// tagEnum returns the enum with names from the enum tag option for the
+// field type. Values are names for string kinds and indexes of names for
+// integer kinds.
+func tagEnum(t reflect.Type, option string) (*enum, error) {
+	if !isEnumKind(t.Kind()) {
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+	e := &enum{
+		names: strings.Split(option, "|"),
+	}
+	for i, name := range e.names {
+		v := reflect.New(t).Elem()
+		switch t.Kind() {
+		case reflect.String:
+			v.SetString(name)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.SetInt(int64(i))
+		default:
+			v.SetUint(uint64(i))
+		}
+		e.values = append(e.values, v)
+	}
+	return e, nil
+}
+
+// value returns the value with the name.
+func (e *enum) value(name string, fold bool) (reflect.Value, bool) {
+	for i, n := range e.names {
+		if n == name {
+			return e.values[i], true
+		}
+	}
+	if fold {
+		for i, n := range e.names {
+			if strings.EqualFold(n, name) {
+				return e.values[i], true
+			}
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// name returns the name of the value.
+func (e *enum) name(v reflect.Value) (string, bool) {
+	for i, ev := range e.values {
+		if ev.Equal(v) {
+			return e.names[i], true
+		}
+	}
+	return "", false
+}
+
+// injectEnum sets the destination of a registered enum type to the enum
+// value for the source. It returns false if the destination type is not a
+// registered enum.
+func (in *injection) injectEnum(srcValue, dstValue reflect.Value, path string) (handled bool, err error) {
+	if in.copying(srcValue, dstValue.Type()) {
+		return false, nil
+	}
+	e := in.registeredEnum(dstValue.Type())
+	if e == nil {
+		return false, nil
+	}
+	return true, in.setEnum(e, srcValue, dstValue, path)
+}
+
+// injectEnumField sets the destination struct field to the enum value for
+// the source, with enum names from the enum tag option.
+func (in *injection) injectEnumField(srcValue, dstValue reflect.Value, option, path string) error {
+	e, err := tagEnum(dstValue.Type(), option)
+	if err != nil {
+		return &InvalidRuleError{
+			Path: path,
+			Rule: "enum=" + option,
+			Err:  err,
+		}
+	}
+	return in.setEnum(e, srcValue, dstValue, path)
+}
+
+// setEnum sets the destination to the enum value with the name from the
+// source string, or to the source value converted to the destination type
+// if it is one of enum values.
+func (in *injection) setEnum(e *enum, srcValue, dstValue reflect.Value, path string) error {
+	for srcValue.Kind() == reflect.Interface || srcValue.Kind() == reflect.Ptr {
+		if srcValue.IsNil() {
+			return injectNil(srcValue, dstValue)
+		}
+		srcValue = srcValue.Elem()
+	}
+	if !srcValue.IsValid() {
+		return injectNil(srcValue, dstValue)
+	}
+	if srcValue.Kind() == reflect.String {
+		if v, ok := e.value(srcValue.String(), in.enumFold); ok {
+			dstValue.Set(v)
+			return nil
+		}
+	}
+	v := reflect.New(dstValue.Type()).Elem()
+	if srcValue.Kind() != reflect.String || srcValue.Type() == dstValue.Type() {
+		if !convertBasic(srcValue, v) {
+			return &InvalidTypeError{
+				TypeSrc: srcValue.Type(),
+				TypeDst: dstValue.Type(),
+			}
+		}
+		if _, ok := e.name(v); ok {
+			dstValue.Set(v)
+			return nil
+		}
+	}
+	return &EnumError{
+		Path:    path,
+		Type:    dstValue.Type(),
+		Value:   valueInterface(srcValue),
+		Allowed: e.names,
+	}
+}
+
+// enumName sets the destination map value to the name of the source value
+// of an integer kind, if it is a value of a registered enum type or of the
+// enum tag option of the struct field, and the destination can hold a string.
+// It returns false if the name is not set.
+func (in *injection) enumName(srcValue, dstValue reflect.Value, field reflect.StructField) bool {
+	if !isIntKind(srcValue.Kind()) {
+		return false
+	}
+	var e *enum
+	if option, ok := tagOption(field.Tag, in.tagKey, "enum"); ok {
+		e, _ = tagEnum(srcValue.Type(), option)
+	} else {
+		e = in.registeredEnum(srcValue.Type())
+	}
+	if e == nil {
+		return false
+	}
+	name, ok := e.name(srcValue)
+	if !ok {
+		return false
+	}
+	switch {
+	case dstValue.Kind() == reflect.String:
+		dstValue.SetString(name)
+	case dstValue.Kind() == reflect.Interface && stringType.AssignableTo(dstValue.Type()):
+		dstValue.Set(reflect.ValueOf(name))
+	default:
+		return false
+	}
+	return true
+}
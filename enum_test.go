@@ -0,0 +1,259 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type EnumLevel string
+
+const (
+	EnumLevelDebug EnumLevel = "debug"
+	EnumLevelInfo  EnumLevel = "info"
+	EnumLevelError EnumLevel = "error"
+)
+
+type EnumColor int
+
+const (
+	EnumColorRed EnumColor = iota + 1
+	EnumColorGreen
+	EnumColorBlue
+)
+
+type EnumConfig struct {
+	Level   EnumLevel            `taint:"level"`
+	Color   EnumColor            `taint:"color"`
+	Colors  []EnumColor          `taint:"colors"`
+	ByLevel map[EnumLevel]string `taint:"by_level"`
+	Format  string               `taint:"format,enum=json|text"`
+	Size    uint8                `taint:"size,enum=small|medium|large"`
+	Mode    int                  `taint:"mode,enum=read|write"`
+}
+
+func newEnumInjector(opts ...Option) *Injector {
+	in := NewInjector(opts...)
+	in.RegisterEnum(EnumLevel(""), map[string]EnumLevel{
+		"debug": EnumLevelDebug,
+		"info":  EnumLevelInfo,
+		"error": EnumLevelError,
+	})
+	in.RegisterEnum(EnumColor(0), map[string]EnumColor{
+		"red":   EnumColorRed,
+		"green": EnumColorGreen,
+		"blue":  EnumColorBlue,
+	})
+	return in
+}
+
+func TestEnum(t *testing.T) {
+	in := newEnumInjector()
+	src := map[string]interface{}{
+		"level":    "info",
+		"color":    "blue",
+		"colors":   []interface{}{"red", 2, EnumColorBlue},
+		"by_level": map[string]interface{}{"error": "e"},
+		"format":   "text",
+		"size":     "medium",
+		"mode":     1,
+	}
+	var d EnumConfig
+	if err := in.Inject(src, &d); err != nil {
+		t.Fatal(err)
+	}
+	expected := EnumConfig{
+		Level:   EnumLevelInfo,
+		Color:   EnumColorBlue,
+		Colors:  []EnumColor{EnumColorRed, EnumColorGreen, EnumColorBlue},
+		ByLevel: map[EnumLevel]string{EnumLevelError: "e"},
+		Format:  "text",
+		Size:    1,
+		Mode:    1,
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+}
+
+func TestEnumCaseInsensitive(t *testing.T) {
+	src := map[string]interface{}{
+		"level":  "DEBUG",
+		"color":  "Green",
+		"format": "JSON",
+	}
+	var d EnumConfig
+	if err := newEnumInjector().Inject(src, &d); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := newEnumInjector(WithCaseInsensitiveEnums()).Inject(src, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Level != EnumLevelDebug || d.Color != EnumColorGreen || d.Format != "json" {
+		t.Errorf("unexpected destination %#v", d)
+	}
+}
+
+func TestEnumError(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		src     map[string]interface{}
+		path    string
+		value   interface{}
+		allowed []string
+	}{
+		{
+			name:    "registered string",
+			src:     map[string]interface{}{"level": "trace"},
+			path:    "Level",
+			value:   "trace",
+			allowed: []string{"debug", "error", "info"},
+		},
+		{
+			name:    "registered int name",
+			src:     map[string]interface{}{"color": "purple"},
+			path:    "Color",
+			value:   "purple",
+			allowed: []string{"blue", "green", "red"},
+		},
+		{
+			name:    "registered int value",
+			src:     map[string]interface{}{"colors": []interface{}{1, 7}},
+			path:    "Colors[1]",
+			value:   7,
+			allowed: []string{"blue", "green", "red"},
+		},
+		{
+			name:    "map key",
+			src:     map[string]interface{}{"by_level": map[string]interface{}{"fatal": "f"}},
+			path:    "ByLevel[fatal]",
+			value:   "fatal",
+			allowed: []string{"debug", "error", "info"},
+		},
+		{
+			name:    "tag option",
+			src:     map[string]interface{}{"format": "xml"},
+			path:    "Format",
+			value:   "xml",
+			allowed: []string{"json", "text"},
+		},
+		{
+			name:    "tag option index",
+			src:     map[string]interface{}{"mode": 2},
+			path:    "Mode",
+			value:   2,
+			allowed: []string{"read", "write"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var d EnumConfig
+			err := newEnumInjector().Inject(tc.src, &d)
+			var eerr *EnumError
+			if !errors.As(err, &eerr) {
+				t.Fatalf("got error %v, want %T", err, eerr)
+			}
+			if eerr.Path != tc.path {
+				t.Errorf("got error path %q, want %q", eerr.Path, tc.path)
+			}
+			if eerr.Value != tc.value {
+				t.Errorf("got error value %#v, want %#v", eerr.Value, tc.value)
+			}
+			if !reflect.DeepEqual(eerr.Allowed, tc.allowed) {
+				t.Errorf("got allowed values %v, want %v", eerr.Allowed, tc.allowed)
+			}
+		})
+	}
+}
+
+func TestEnumErrorMessage(t *testing.T) {
+	err := &EnumError{
+		Path:    "Level",
+		Type:    reflect.TypeOf(EnumLevel("")),
+		Value:   "trace",
+		Allowed: []string{"debug", "info"},
+	}
+	expected := `taint: inject Level: unknown taint.EnumLevel value "trace", allowed: debug, info`
+	if err.Error() != expected {
+		t.Errorf("got error message %q, want %q", err.Error(), expected)
+	}
+}
+
+func TestEnumInvalidRule(t *testing.T) {
+	type invalid struct {
+		Ratio float64 `taint:"ratio,enum=a|b"`
+	}
+	var d invalid
+	err := Inject(map[string]interface{}{"ratio": "a"}, &d)
+	var rerr *InvalidRuleError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("got error %v, want %T", err, rerr)
+	}
+}
+
+func TestEnumExtraction(t *testing.T) {
+	in := newEnumInjector()
+	src := EnumConfig{
+		Level:   EnumLevelError,
+		Color:   EnumColorGreen,
+		Colors:  []EnumColor{EnumColorRed},
+		ByLevel: map[EnumLevel]string{EnumLevelInfo: "i"},
+		Format:  "json",
+		Size:    2,
+		Mode:    5,
+	}
+	var m map[string]interface{}
+	if err := in.Inject(src, &m); err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string]interface{}{
+		"level": EnumLevelError,
+		"color": "green",
+		"size":  "large",
+		// Values without names are not replaced.
+		"mode": 5,
+	} {
+		if m[key] != expected {
+			t.Errorf("got %s %#v, want %#v", key, m[key], expected)
+		}
+	}
+
+	var back EnumConfig
+	if err := in.Inject(m, &back); err == nil {
+		t.Fatal("expected error for the mode value without a name")
+	}
+	delete(m, "mode")
+	if err := in.Inject(m, &back); err != nil {
+		t.Fatal(err)
+	}
+	src.Mode = 0
+	if !reflect.DeepEqual(back, src) {
+		t.Errorf("%T destination %#v is not set to %#v", back, back, src)
+	}
+}
+
+func TestRegisterEnumPanics(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		example interface{}
+		values  interface{}
+	}{
+		{name: "nil", example: nil, values: map[string]EnumLevel{}},
+		{name: "float", example: 1.5, values: map[string]float64{}},
+		{name: "not map", example: EnumLevel(""), values: []EnumLevel{}},
+		{name: "value type", example: EnumLevel(""), values: map[string]string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			NewInjector().RegisterEnum(tc.example, tc.values)
+		})
+	}
+}
@@ -5,7 +5,12 @@
 
 package taint
 
-import "reflect"
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
 
 // InvalidInjectError defines an error type for invalid inject type.
 type InvalidInjectError struct {
@@ -39,5 +44,204 @@ type InvalidTypeError struct {
 }
 
 func (e *InvalidTypeError) Error() string {
-	return "taint: inject source type " + e.TypeSrc.String() + " != destination type " + e.TypeDst.String()
+	return "taint: inject source type " + typeString(e.TypeSrc) + " != destination type " + typeString(e.TypeDst)
+}
+
+func typeString(t reflect.Type) string {
+	if t == nil {
+		return "nil"
+	}
+	return t.String()
+}
+
+// ValidationError defines an error type for injected field values that do not
+// satisfy a validation rule from the struct tag.
+type ValidationError struct {
+	Path  string
+	Rule  string
+	Value interface{}
+}
+
+func (e *ValidationError) Error() string {
+	return "taint: inject field " + e.Path + " does not satisfy rule " + e.Rule
+}
+
+// InvalidRuleError defines an error type for validation rules in struct tags
+// that can not be evaluated, because of a malformed argument or an unsupported
+// field type.
+type InvalidRuleError struct {
+	Path string
+	Rule string
+	Err  error
+}
+
+func (e *InvalidRuleError) Error() string {
+	return "taint: inject field " + e.Path + " invalid rule " + e.Rule + ": " + e.Err.Error()
+}
+
+func (e *InvalidRuleError) Unwrap() error {
+	return e.Err
+}
+
+// ValidatorError defines an error type for errors returned by the Validate
+// method of the Validator interface.
+type ValidatorError struct {
+	Path string
+	Err  error
+}
+
+func (e *ValidatorError) Error() string {
+	if e.Path == "" {
+		return "taint: inject validate: " + e.Err.Error()
+	}
+	return "taint: inject validate " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *ValidatorError) Unwrap() error {
+	return e.Err
+}
+
+// UnexportedFieldError defines an error type for source values that can not
+// be injected into unexported destination struct fields.
+type UnexportedFieldError struct {
+	Path string
+}
+
+func (e *UnexportedFieldError) Error() string {
+	return "taint: inject unexported field " + e.Path
+}
+
+// CycleError defines an error type for cycles in the source that can not be
+// injected. Path is the location of the reference that closes the cycle and
+// Ref is the location where the referenced value is injected.
+type CycleError struct {
+	Path string
+	Ref  string
+}
+
+func (e *CycleError) Error() string {
+	ref := e.Ref
+	if ref == "" {
+		ref = "root"
+	}
+	return "taint: inject cycle at " + e.Path + " referencing " + ref
+}
+
+// LimitError defines an error type for sources that exceed limits configured
+// on Injector. Limit is one of LimitDepth, LimitLength, LimitMapEntries or
+// LimitElements.
+type LimitError struct {
+	Path  string
+	Limit string
+	Max   int
+	Value int
+}
+
+func (e *LimitError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "root"
+	}
+	return "taint: inject " + path + " exceeds " + e.Limit + " limit " + strconv.Itoa(e.Max)
+}
+
+// ContextError defines an error type for injections that are stopped because
+// the context is canceled.
+type ContextError struct {
+	Path string
+	Err  error
+}
+
+func (e *ContextError) Error() string {
+	if e.Path == "" {
+		return "taint: inject: " + e.Err.Error()
+	}
+	return "taint: inject " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *ContextError) Unwrap() error {
+	return e.Err
+}
+
+// HookError defines an error type for errors returned by BeforeInject and
+// AfterInject methods and field hooks.
+type HookError struct {
+	Path string
+	Err  error
+}
+
+func (e *HookError) Error() string {
+	if e.Path == "" {
+		return "taint: inject hook: " + e.Err.Error()
+	}
+	return "taint: inject hook " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// ConvertError defines an error type for errors returned by converters.
+type ConvertError struct {
+	Path string
+	Type reflect.Type
+	Err  error
+}
+
+func (e *ConvertError) Error() string {
+	return "taint: inject convert " + e.Path + " to " + typeString(e.Type) + ": " + e.Err.Error()
+}
+
+func (e *ConvertError) Unwrap() error {
+	return e.Err
+}
+
+// KeyCollisionError defines an error type for distinct source map keys that
+// are converted to the same destination map key.
+type KeyCollisionError struct {
+	Path string
+	Key  interface{}
+}
+
+func (e *KeyCollisionError) Error() string {
+	return "taint: inject map key collision at " + e.Path + ": " + fmt.Sprint(e.Key)
+}
+
+// EnumError defines an error type for source values that are not names or
+// values of an enum registered with Injector.RegisterEnum or listed by the
+// enum tag option.
+type EnumError struct {
+	Path    string
+	Type    reflect.Type
+	Value   interface{}
+	Allowed []string
+}
+
+func (e *EnumError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "root"
+	}
+	return "taint: inject " + path + ": unknown " + typeString(e.Type) + " value " + strconv.Quote(fmt.Sprint(e.Value)) + ", allowed: " + strings.Join(e.Allowed, ", ")
+}
+
+// VariantError defines an error type for sources of interface destinations
+// with registered variants that do not have the variant key, or that have a
+// variant name that is not registered for the interface type.
+type VariantError struct {
+	Path string
+	Type reflect.Type
+	Key  string
+	Name string
+}
+
+func (e *VariantError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "root"
+	}
+	if e.Name == "" {
+		return "taint: inject " + path + ": missing variant key " + strconv.Quote(e.Key) + " for " + typeString(e.Type)
+	}
+	return "taint: inject " + path + ": unknown variant " + strconv.Quote(e.Name) + " of " + typeString(e.Type)
 }
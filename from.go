@@ -0,0 +1,107 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// lookupFrom returns the source value for the from tag option. The option
+// value is a list of alternative paths separated by "|" that are tried in
+// order, and the value of the first path that is present in the source is
+// returned. Paths consist of struct field names or map keys separated by
+// dots, and slice or array indexes or map keys in square brackets, like
+// Address.Street, Tags[0] or Labels[env].Value. If no path is present, the
+// field is looked up by its key name.
+func lookupFrom(srcValue reflect.Value, from string) (reflect.Value, bool) {
+	for _, p := range strings.Split(from, "|") {
+		if v, ok := lookupPath(srcValue, p); ok {
+			return v, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func lookupPath(v reflect.Value, path string) (reflect.Value, bool) {
+	for path != "" {
+		var segment string
+		var index bool
+		switch path[0] {
+		case '.':
+			path = path[1:]
+			continue
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if end < 0 {
+				return reflect.Value{}, false
+			}
+			segment, path = path[1:end], path[end+1:]
+			index = true
+		default:
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			segment, path = path[:end], path[end:]
+		}
+		var ok bool
+		v, ok = lookupSegment(v, segment, index)
+		if !ok {
+			return reflect.Value{}, false
+		}
+	}
+	return v, true
+}
+
+// lookupSegment returns the value of a struct field, a map key, a slice or
+// array element, or a Source key or element.
+func lookupSegment(v reflect.Value, segment string, index bool) (reflect.Value, bool) {
+	if src, ok := asSource(v); ok {
+		if i, err := strconv.Atoi(segment); err == nil && index {
+			if i < 0 || i >= src.Len() {
+				return reflect.Value{}, false
+			}
+			return reflect.ValueOf(src.Index(i)), true
+		}
+		e, ok := src.Lookup(segment)
+		return reflect.ValueOf(e), ok
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if index {
+			return reflect.Value{}, false
+		}
+		return exportedFieldByName(v, segment)
+	case reflect.Map:
+		key, ok := stringKey(segment, v.Type().Key())
+		if !ok {
+			key = reflect.New(v.Type().Key()).Elem()
+			if !convertBasic(reflect.ValueOf(segment), key) {
+				return reflect.Value{}, false
+			}
+		}
+		e := v.MapIndex(key)
+		return e, e.IsValid()
+	case reflect.Slice, reflect.Array:
+		if !index {
+			return reflect.Value{}, false
+		}
+		i, err := strconv.Atoi(segment)
+		if err != nil || i < 0 || i >= v.Len() {
+			return reflect.Value{}, false
+		}
+		return v.Index(i), true
+	}
+	return reflect.Value{}, false
+}
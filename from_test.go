@@ -0,0 +1,155 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type userDTO struct {
+	ID       int
+	FullName string
+	Nick     string
+	Address  *addressDTO
+	Tags     []string
+	Phones   map[string]string
+	Contacts []contactDTO
+}
+
+type addressDTO struct {
+	Street string
+	City   string
+}
+
+type contactDTO struct {
+	Kind  string
+	Value string
+}
+
+type UserModel struct {
+	ID        int
+	Name      string `taint:",from=Name|Nick|FullName"`
+	Street    string `taint:"from=Address.Street"`
+	City      string `taint:"city,from=Address.City"`
+	FirstTag  string `taint:",from=Tags[0]"`
+	ThirdTag  string `taint:",from=Tags[2]"`
+	HomePhone string `taint:",from=Phones[home]"`
+	Email     string `taint:",from=Contacts[1].Value"`
+}
+
+func TestInjectFrom(t *testing.T) {
+	s := userDTO{
+		ID:       1,
+		FullName: "Alice Smith",
+		Nick:     "alice",
+		Address: &addressDTO{
+			Street: "Main Street",
+			City:   "Springfield",
+		},
+		Tags:   []string{"admin", "dev"},
+		Phones: map[string]string{"home": "123"},
+		Contacts: []contactDTO{
+			{Kind: "phone", Value: "123"},
+			{Kind: "email", Value: "alice@example.com"},
+		},
+	}
+	expected := UserModel{
+		ID:        1,
+		Name:      "alice",
+		Street:    "Main Street",
+		City:      "Springfield",
+		FirstTag:  "admin",
+		HomePhone: "123",
+		Email:     "alice@example.com",
+	}
+	var d UserModel
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+}
+
+func TestInjectFromMissingPaths(t *testing.T) {
+	s := struct {
+		FullName string
+		Address  *addressDTO
+		Tags     []string
+	}{
+		FullName: "Alice Smith",
+		Tags:     []string{"admin"},
+	}
+	expected := UserModel{
+		Name:     "Alice Smith",
+		FirstTag: "admin",
+	}
+	var d UserModel
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+}
+
+func TestInjectFromMap(t *testing.T) {
+	s := map[interface{}]interface{}{
+		"Nick": "alice",
+		"Address": map[interface{}]interface{}{
+			"City": "Springfield",
+		},
+		"Tags": []interface{}{"admin"},
+	}
+	expected := UserModel{
+		Name:     "alice",
+		City:     "Springfield",
+		FirstTag: "admin",
+	}
+	var d UserModel
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+}
+
+func TestInjectFromSameType(t *testing.T) {
+	s := UserModel{
+		ID:        1,
+		Name:      "alice",
+		Street:    "Main Street",
+		City:      "Springfield",
+		FirstTag:  "admin",
+		ThirdTag:  "ops",
+		HomePhone: "123",
+		Email:     "alice@example.com",
+	}
+	var d UserModel
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, s) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, s)
+	}
+}
+
+func TestInjectFromRequired(t *testing.T) {
+	var d struct {
+		Street string `taint:"street,required,from=Address.Street"`
+	}
+	err := Inject(userDTO{}, &d)
+	var ferr *FieldRequiredError
+	if !errors.As(err, &ferr) {
+		t.Fatalf("Expected FieldRequiredError, but got %#v", err)
+	}
+	if ferr.FieldName != "street" {
+		t.Errorf("Expected FieldRequiredError FieldName street, but got %#v", ferr.FieldName)
+	}
+}
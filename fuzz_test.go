@@ -0,0 +1,165 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type fuzzInner struct {
+	Name   string `taint:"name,required"`
+	Values []int  `taint:"values,max=3"`
+	Ratio  float64
+	Any    interface{}
+}
+
+type fuzzEmbedded struct {
+	Embedded string
+}
+
+type fuzzShape struct {
+	*fuzzEmbedded
+	ID      int    `taint:"id"`
+	Name    string `taint:"name,nonzero"`
+	Skip    string `taint:"-"`
+	Tags    []string
+	Labels  map[string]string
+	Inner   fuzzInner
+	Inners  []fuzzInner
+	Ptr     *fuzzInner
+	Any     interface{}
+	Array   [2]string
+	Matrix  [][]float64
+	Nested  map[string][]fuzzInner
+	Reader  io.Reader
+	Keys    map[int]string
+	From    interface{} `taint:"from=Inner.name"`
+	private string
+}
+
+// fuzzSource is a Source of a decoded JSON object that returns nil values
+// with true for null values.
+type fuzzSource map[string]interface{}
+
+func (s fuzzSource) Keys() []string {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s fuzzSource) Lookup(key string) (interface{}, bool) {
+	v, ok := s[key]
+	return v, ok
+}
+
+func (s fuzzSource) Len() int {
+	return len(s)
+}
+
+func (s fuzzSource) Index(i int) interface{} {
+	return s[s.Keys()[i]]
+}
+
+// fuzzValue converts values decoded from JSON to a wider set of types, so
+// that integers, maps with interface keys, Sources and typed nil pointers are
+// also injected.
+func fuzzValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int(v)
+		}
+		return v
+	case []interface{}:
+		for i, e := range v {
+			if e == nil && i%2 == 1 {
+				v[i] = (*fuzzInner)(nil)
+				continue
+			}
+			v[i] = fuzzValue(e)
+		}
+		return v
+	case map[string]interface{}:
+		if len(v)%2 == 1 {
+			m := make(map[interface{}]interface{}, len(v))
+			for k, e := range v {
+				m[k] = fuzzValue(e)
+			}
+			return m
+		}
+		for k, e := range v {
+			v[k] = fuzzValue(e)
+		}
+		if len(v)%4 == 2 {
+			return fuzzSource(v)
+		}
+		return v
+	}
+	return v
+}
+
+func FuzzInject(f *testing.F) {
+	for _, seed := range []string{
+		`{}`,
+		`[]`,
+		`null`,
+		`"string"`,
+		`42`,
+		`{"id": 1, "name": "test", "Tags": ["a", "b"], "Labels": {"a": "b"}}`,
+		`{"Inner": {"name": "inner", "values": [1, 2, 3, 4]}, "Inners": [{"name": "a"}, null, 3]}`,
+		`{"Ptr": {"name": "ptr"}, "Any": [1, {"a": null}], "Array": ["a", "b", "c"]}`,
+		`{"Matrix": [[1.5, 2], [], null], "Nested": {"a": [{"name": "n", "Ratio": 0.5}]}}`,
+		`{"Reader": "r", "Keys": {"1": "one"}, "private": "p", "Embedded": "e", "Skip": "s"}`,
+		`[{"id": "1"}, {"name": ""}, [1, 2], {"Inners": {"name": "x"}}]`,
+		`{"a": {"b": {"c": [1, [2, [3]]]}}}`,
+		`{"name": null, "Inner": {"name": null, "Ratio": 1}, "Tags": null, "Any": {"x": null, "y": 1}}`,
+	} {
+		f.Add([]byte(seed))
+	}
+	hooked := NewInjector(WithFieldHook(func(_ context.Context, _ string, src, _ interface{}) (interface{}, error) {
+		return src, nil
+	}))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var v interface{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			t.Skip()
+		}
+		src := fuzzValue(v)
+		for _, dst := range []interface{}{
+			new(fuzzShape),
+			new([]fuzzShape),
+			new(map[string]fuzzShape),
+			new(map[int]fuzzInner),
+			new([3]fuzzInner),
+			new(fuzzInner),
+			new([]*fuzzInner),
+			new(map[string]interface{}),
+			new(interface{}),
+			new(io.Reader),
+			new(int),
+			new(string),
+			new([2][]string),
+		} {
+			_ = hooked.Inject(src, reflect.New(reflect.TypeOf(dst).Elem()).Interface())
+			if err := Inject(src, dst); err != nil {
+				continue
+			}
+			var m map[string]interface{}
+			_ = Inject(dst, &m)
+			var s fuzzShape
+			_ = Inject(dst, &s)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import "reflect"
+
+// GeneratedTagKey is the key for Go struct tags that the taintgen command
+// generates methods for.
+const GeneratedTagKey = "taint"
+
+// MapInjector is implemented by struct types with the InjectFromMap method
+// generated by the taintgen command. It injects values from the map into
+// fields of the struct in the same way as Inject, without reflection.
+type MapInjector interface {
+	InjectFromMap(m map[string]interface{}) error
+}
+
+// MapExtractor is implemented by struct types with the ToMap method
+// generated by the taintgen command. It returns fields of the struct as a
+// map in the same way as Inject into a map, without reflection.
+type MapExtractor interface {
+	ToMap() map[string]interface{}
+}
+
+// Copier is implemented by struct types with the CopyTo and CopyToAny
+// methods generated by the taintgen command. CopyToAny injects fields of the
+// struct into the destination, that must be a pointer to the struct that
+// CopyTo accepts, in the same way as Inject, without reflection. It returns
+// false if the destination is of any other type.
+type Copier interface {
+	CopyToAny(dst interface{}) (bool, error)
+}
+
+var (
+	mapType          = reflect.TypeOf(map[string]interface{}(nil))
+	mapExtractorType = reflect.TypeOf((*MapExtractor)(nil)).Elem()
+	copierType       = reflect.TypeOf((*Copier)(nil)).Elem()
+)
+
+// useGenerated returns true if the Injector is configured in a way that
+// methods generated by the taintgen command give the same result as the
+// reflection.
+func (in *Injector) useGenerated() bool {
+	return in.tagKey == GeneratedTagKey &&
+		len(in.fieldHooks) == 0 &&
+		len(in.converters) == 0 &&
+		!in.unexportedFieldError &&
+		in.maxDepth == 0 &&
+		in.maxLength == 0 &&
+		in.maxMapEntries == 0 &&
+		in.maxElements == 0 &&
+		!in.valueConversion &&
+		!in.hasEnums()
+}
+
+// injectGenerated injects the source into the destination with methods
+// generated by the taintgen command. A map[string]interface{} is injected
+// into a struct with the MapInjector interface, a struct into a
+// map[string]interface{} with the MapExtractor interface, and a struct into
+// another struct with the Copier interface. It returns false if the source
+// and the destination do not have generated methods.
+func (in *injection) injectGenerated(srcValue, dstValue reflect.Value) (handled bool, err error) {
+	if !in.useGenerated() || in.recording() || !srcValue.CanInterface() {
+		return false, nil
+	}
+	switch {
+	case srcValue.Type() == mapType && dstValue.Kind() == reflect.Struct:
+		d, ok := dstValue.Addr().Interface().(MapInjector)
+		if !ok {
+			return false, nil
+		}
+		return true, d.InjectFromMap(srcValue.Interface().(map[string]interface{}))
+	case srcValue.Kind() == reflect.Struct && dstValue.Type() == mapType:
+		if !srcValue.Type().Implements(mapExtractorType) {
+			return false, nil
+		}
+		dstValue.Set(reflect.ValueOf(srcValue.Interface().(MapExtractor).ToMap()))
+		return true, nil
+	case srcValue.Kind() == reflect.Struct && dstValue.Kind() == reflect.Struct:
+		if !srcValue.Type().Implements(copierType) {
+			return false, nil
+		}
+		return srcValue.Interface().(Copier).CopyToAny(dstValue.Addr().Interface())
+	}
+	return false, nil
+}
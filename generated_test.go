@@ -0,0 +1,156 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// generatedUser has methods in the same form as those generated by the
+// taintgen command, that record their calls.
+type generatedUser struct {
+	Name  string `taint:"name" json:"name"`
+	calls []string
+}
+
+func (x *generatedUser) InjectFromMap(m map[string]interface{}) error {
+	x.calls = append(x.calls, "InjectFromMap")
+	if v, ok := m["name"]; ok {
+		if w, ok := v.(string); ok {
+			x.Name = w
+		} else if err := Inject(v, &x.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x generatedUser) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"name":      x.Name,
+		"generated": true,
+	}
+}
+
+func (x generatedUser) CopyTo(o *generatedUserDTO) error {
+	o.Name = x.Name
+	o.Generated = true
+	return nil
+}
+
+func (x generatedUser) CopyToAny(dst interface{}) (bool, error) {
+	o, ok := dst.(*generatedUserDTO)
+	if !ok {
+		return false, nil
+	}
+	return true, x.CopyTo(o)
+}
+
+func (x *generatedUser) Validate() error {
+	if x.Name == "" {
+		return errors.New("empty name")
+	}
+	return nil
+}
+
+type generatedUserDTO struct {
+	Name      string
+	Generated bool
+}
+
+func TestInjectGenerated(t *testing.T) {
+	var u generatedUser
+	if err := Inject(map[string]interface{}{"name": "alice"}, &u); err != nil {
+		t.Fatal(err)
+	}
+	expected := generatedUser{
+		Name:  "alice",
+		calls: []string{"InjectFromMap"},
+	}
+	if !reflect.DeepEqual(u, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", u, u, expected)
+	}
+
+	var m map[string]interface{}
+	if err := Inject(u, &m); err != nil {
+		t.Fatal(err)
+	}
+	expectedMap := map[string]interface{}{
+		"name":      "alice",
+		"generated": true,
+	}
+	if !reflect.DeepEqual(m, expectedMap) {
+		t.Errorf("%T destination %#v is not set to %#v", m, m, expectedMap)
+	}
+
+	var d generatedUserDTO
+	if err := Inject(u, &d); err != nil {
+		t.Fatal(err)
+	}
+	expectedDTO := generatedUserDTO{
+		Name:      "alice",
+		Generated: true,
+	}
+	if !reflect.DeepEqual(d, expectedDTO) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expectedDTO)
+	}
+
+	// Destinations that CopyTo does not accept are injected with the
+	// reflection.
+	var o struct{ Name string }
+	if err := Inject(u, &o); err != nil {
+		t.Fatal(err)
+	}
+	if o.Name != "alice" {
+		t.Errorf("%T destination %#v is not set", o, o)
+	}
+}
+
+func TestInjectGeneratedValidator(t *testing.T) {
+	var u generatedUser
+	err := Inject(map[string]interface{}{"name": ""}, &u)
+	var verr *ValidatorError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected ValidatorError, but got %#v", err)
+	}
+	if len(u.calls) != 1 {
+		t.Errorf("Expected InjectFromMap call, but got %v", u.calls)
+	}
+}
+
+func TestInjectGeneratedNotUsed(t *testing.T) {
+	hook := WithFieldHook(func(_ context.Context, _ string, src, _ interface{}) (interface{}, error) {
+		return src, nil
+	})
+	for _, in := range []*Injector{
+		NewInjector(hook),
+		NewInjector(WithTagKey("json")),
+		NewInjector(WithMaxDepth(10)),
+		NewInjector(WithValueConversion()),
+		NewInjector(WithConverter("", func(_ context.Context, src interface{}) (interface{}, error) {
+			return src, nil
+		})),
+	} {
+		var u generatedUser
+		if err := in.Inject(map[string]interface{}{"name": "alice"}, &u); err != nil {
+			t.Fatal(err)
+		}
+		if u.calls != nil {
+			t.Errorf("Expected no generated method calls, but got %v", u.calls)
+		}
+
+		var m map[string]interface{}
+		if err := in.Inject(u, &m); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := m["generated"]; ok {
+			t.Errorf("Expected map without generated key, but got %#v", m)
+		}
+	}
+}
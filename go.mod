@@ -1,3 +1,3 @@
 module resenje.org/taint
 
-go 1.13
+go 1.23
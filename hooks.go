@@ -0,0 +1,115 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+// BeforeInjector is implemented by destination types that need to inspect
+// or prepare for the source value before it is injected. If the destination,
+// or a pointer to it, implements BeforeInjector, its BeforeInject method is
+// called with the source value before the injection.
+type BeforeInjector interface {
+	BeforeInject(src interface{}) error
+}
+
+// AfterInjector is implemented by destination types that need to normalize
+// their values or fill derived fields after the injection. If the
+// destination, or a pointer to it, implements AfterInjector, its AfterInject
+// method is called after the source value is injected.
+type AfterInjector interface {
+	AfterInject() error
+}
+
+// FieldHook is a function that is called by Injector before a destination
+// struct field is injected. It receives the context of the injection, the
+// path of the field, the source value for the field and a pointer to the
+// destination field. The returned value replaces the source value, so hooks
+// that do not need to change it should return src. If ErrSkipField is
+// returned, the field is left unchanged. Any other error stops the
+// injection.
+type FieldHook func(ctx context.Context, path string, src, dst interface{}) (interface{}, error)
+
+// ErrSkipField can be returned by FieldHook to skip the injection of a
+// field.
+var ErrSkipField = errors.New("taint: skip field")
+
+func callBeforeInject(srcValue, dstValue reflect.Value, path string) error {
+	d, ok := ptrInterface(dstValue).(BeforeInjector)
+	if !ok {
+		return nil
+	}
+	var src interface{}
+	if srcValue.IsValid() && srcValue.CanInterface() {
+		src = srcValue.Interface()
+	}
+	if err := d.BeforeInject(src); err != nil {
+		return &HookError{
+			Path: path,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+func callAfterInject(dstValue reflect.Value, path string) error {
+	d, ok := ptrInterface(dstValue).(AfterInjector)
+	if !ok {
+		return nil
+	}
+	if err := d.AfterInject(); err != nil {
+		return &HookError{
+			Path: path,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// ptrInterface returns the pointer to the destination as an interface.
+func ptrInterface(dstValue reflect.Value) interface{} {
+	if !dstValue.CanInterface() {
+		return nil
+	}
+	return dstValue.Interface()
+}
+
+// callFieldHooks calls all field hooks for the pointer to the destination
+// field in order, returning the final source value, or skip as true if any
+// hook returned ErrSkipField.
+func (in *injection) callFieldHooks(path string, srcValue, dstValue reflect.Value) (v reflect.Value, skip bool, err error) {
+	if len(in.fieldHooks) == 0 {
+		return srcValue, false, nil
+	}
+	dst := ptrInterface(dstValue)
+	if dst == nil {
+		return srcValue, false, nil
+	}
+	// Sources may return nil values, which are passed to hooks as nil.
+	var src interface{}
+	if srcValue.IsValid() {
+		if !srcValue.CanInterface() {
+			return srcValue, false, nil
+		}
+		src = srcValue.Interface()
+	}
+	for _, hook := range in.fieldHooks {
+		src, err = hook(in.ctx, path, src, dst)
+		if errors.Is(err, ErrSkipField) {
+			return srcValue, true, nil
+		}
+		if err != nil {
+			return srcValue, false, &HookError{
+				Path: path,
+				Err:  err,
+			}
+		}
+	}
+	return reflect.ValueOf(src), false, nil
+}
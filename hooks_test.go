@@ -0,0 +1,252 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type Contact struct {
+	Name   string
+	Email  string
+	Domain string
+	source interface{}
+}
+
+func (c *Contact) BeforeInject(src interface{}) error {
+	if src == nil {
+		return errors.New("empty contact")
+	}
+	c.source = src
+	return nil
+}
+
+func (c *Contact) AfterInject() error {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Email = strings.ToLower(c.Email)
+	if i := strings.LastIndex(c.Email, "@"); i >= 0 {
+		c.Domain = c.Email[i+1:]
+	}
+	return nil
+}
+
+type Team struct {
+	Lead    Contact
+	Members []Contact
+}
+
+func TestBeforeAfterInject(t *testing.T) {
+	s := map[string]interface{}{
+		"Lead": map[string]interface{}{
+			"Name":  " Alice ",
+			"Email": "Alice@Example.com",
+		},
+		"Members": []interface{}{
+			map[string]interface{}{
+				"Name":  "Bob\n",
+				"Email": "BOB@example.org",
+			},
+		},
+	}
+	var d Team
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d.Lead.source, s["Lead"]) {
+		t.Errorf("got source %#v, want %#v", d.Lead.source, s["Lead"])
+	}
+	d.Lead.source = nil
+	d.Members[0].source = nil
+	expected := Team{
+		Lead: Contact{
+			Name:   "Alice",
+			Email:  "alice@example.com",
+			Domain: "example.com",
+		},
+		Members: []Contact{
+			{
+				Name:   "Bob",
+				Email:  "bob@example.org",
+				Domain: "example.org",
+			},
+		},
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+}
+
+func TestAfterInjectFromStruct(t *testing.T) {
+	s := struct {
+		Lead struct{ Name, Email string }
+	}{
+		Lead: struct{ Name, Email string }{
+			Name:  " Alice ",
+			Email: "Alice@Example.com",
+		},
+	}
+	var d Team
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Lead.Name != "Alice" || d.Lead.Domain != "example.com" {
+		t.Errorf("unexpected destination %#v", d.Lead)
+	}
+}
+
+func TestBeforeInjectError(t *testing.T) {
+	s := map[string]interface{}{
+		"Members": []interface{}{
+			map[string]interface{}{},
+			nil,
+		},
+	}
+	var d Team
+	err := Inject(s, &d)
+	var herr *HookError
+	if !errors.As(err, &herr) {
+		t.Fatalf("Expected HookError, but got %#v", err)
+	}
+	if herr.Path != "Members[1]" {
+		t.Errorf("got path %q, want %q", herr.Path, "Members[1]")
+	}
+}
+
+func TestFieldHook(t *testing.T) {
+	type Account struct {
+		Username string
+		Password string
+		Age      int
+		Note     string
+	}
+	var paths []string
+	in := NewInjector(
+		WithFieldHook(func(_ context.Context, path string, src, dst interface{}) (interface{}, error) {
+			paths = append(paths, path)
+			if s, ok := src.(string); ok {
+				return strings.TrimSpace(s), nil
+			}
+			return src, nil
+		}),
+		WithFieldHook(func(_ context.Context, path string, src, dst interface{}) (interface{}, error) {
+			switch path {
+			case "Password":
+				return nil, ErrSkipField
+			case "Note":
+				return src.(string) + " (was " + *dst.(*string) + ")", nil
+			}
+			return src, nil
+		}),
+	)
+	s := map[string]interface{}{
+		"Username": "  alice ",
+		"Password": "secret",
+		"Age":      42,
+		"Note":     " new ",
+	}
+	d := Account{
+		Password: "unchanged",
+		Note:     "old",
+	}
+	if err := in.Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	expected := Account{
+		Username: "alice",
+		Password: "unchanged",
+		Age:      42,
+		Note:     "new (was old)",
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+	expectedPaths := []string{"Username", "Password", "Age", "Note"}
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Errorf("got paths %v, want %v", paths, expectedPaths)
+	}
+}
+
+func TestFieldHookNilSource(t *testing.T) {
+	type Config struct {
+		Name  string
+		Note  *string
+		Inner struct {
+			Name string
+		}
+		From *string `taint:"from=Inner.Missing"`
+	}
+	var got []interface{}
+	in := NewInjector(WithFieldHook(func(_ context.Context, path string, src, dst interface{}) (interface{}, error) {
+		got = append(got, src)
+		if path == "Name" {
+			return nil, fmt.Errorf("name: %w", ErrSkipField)
+		}
+		return src, nil
+	}))
+	src := configNode{
+		props:   map[string]string{"Inner.Name": "inner"},
+		lookups: new([]string),
+	}
+	d := Config{Name: "unchanged"}
+	if err := in.Inject(nilSource{src}, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Name != "unchanged" || d.Note != nil || d.Inner.Name != "inner" || d.From != nil {
+		t.Errorf("%T destination %#v is not set", d, d)
+	}
+	if len(got) != 5 || got[0] != nil || got[1] != nil || got[4] != nil {
+		t.Errorf("got hook sources %#v", got)
+	}
+}
+
+// nilSource is a Source that returns nil values for keys that the wrapped
+// Source does not have.
+type nilSource struct {
+	Source
+}
+
+func (s nilSource) Lookup(key string) (interface{}, bool) {
+	if v, ok := s.Source.Lookup(key); ok {
+		if v, ok := v.(Source); ok {
+			return nilSource{v}, true
+		}
+		return v, true
+	}
+	return nil, true
+}
+
+func TestFieldHookError(t *testing.T) {
+	errVeto := errors.New("veto")
+	in := NewInjector(WithFieldHook(func(_ context.Context, path string, src, dst interface{}) (interface{}, error) {
+		if path == "Lead.Email" {
+			return nil, errVeto
+		}
+		return src, nil
+	}))
+	s := map[string]interface{}{
+		"Lead": map[string]interface{}{
+			"Name":  "Alice",
+			"Email": "alice@example.com",
+		},
+	}
+	var d Team
+	err := in.Inject(s, &d)
+	var herr *HookError
+	if !errors.As(err, &herr) {
+		t.Fatalf("Expected HookError, but got %#v", err)
+	}
+	if herr.Path != "Lead.Email" {
+		t.Errorf("got path %q, want %q", herr.Path, "Lead.Email")
+	}
+	if !errors.Is(err, errVeto) {
+		t.Errorf("got error %v, want %v", err, errVeto)
+	}
+}
@@ -6,9 +6,10 @@
 package taint // import "resenje.org/taint"
 
 import (
-	"errors"
+	"context"
+	"fmt"
 	"reflect"
-	"runtime"
+	"strconv"
 	"strings"
 )
 
@@ -25,33 +26,128 @@ func Inject(src, dst interface{}) error {
 // InjectWithTag will inject fields of source object into destination object
 // using a custom Go struct tag.
 func InjectWithTag(src, dst interface{}, tagKey string) error {
+	return NewInjector(WithTagKey(tagKey)).Inject(src, dst)
+}
+
+// InjectContext will inject fields of source object into destination object.
+// Injection is stopped if the context is canceled.
+func InjectContext(ctx context.Context, src, dst interface{}) error {
+	return NewInjector().InjectContext(ctx, src, dst)
+}
+
+// Inject will inject fields of source object into destination object.
+func (in *Injector) Inject(src, dst interface{}) error {
+	return in.InjectContext(context.Background(), src, dst)
+}
+
+// InjectContext will inject fields of source object into destination object.
+// The context is checked periodically while slices, arrays, maps and structs
+// are injected, and the injection is stopped with ContextError if the context
+// is canceled. The context is passed to field hooks and converters.
+func (in *Injector) InjectContext(ctx context.Context, src, dst interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return &ContextError{
+			Err: err,
+		}
+	}
 	dstValue := reflect.ValueOf(dst)
+	if !dstValue.IsValid() {
+		return &InvalidInjectError{}
+	}
 	if dstValue.Kind() != reflect.Ptr || dstValue.IsNil() {
 		return &InvalidInjectError{dstValue.Type()}
 	}
-	return inject(reflect.ValueOf(src), dstValue, tagKey)
+	i := in.newInjection(ctx)
+	defer i.release()
+	return i.inject(reflect.ValueOf(src), dstValue, "")
+}
+
+// inject calls BeforeInject and AfterInject methods of the destination around
+// the injection of the source value, unless the value is deep copied. The
+// destination value must be a non-nil pointer to the value that is set.
+func (in *injection) inject(srcValue, dstValue reflect.Value, path string) error {
+	if err := in.enter(path); err != nil {
+		return err
+	}
+	defer in.leave()
+	if err := in.checkContext(path); err != nil {
+		return err
+	}
+	copying := in.copying(srcValue, dstValue.Type().Elem())
+	if !copying {
+		if err := callBeforeInject(srcValue, dstValue, path); err != nil {
+			return err
+		}
+	}
+	in.replace(srcValue, dstValue.Elem(), path)
+	if err := in.injectValue(srcValue, dstValue, path); err != nil {
+		return err
+	}
+	in.record(srcValue, dstValue.Elem(), path)
+	if copying {
+		return nil
+	}
+	return callAfterInject(dstValue, path)
 }
 
-func inject(srcValue, dstValue reflect.Value, tagKey string) (err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			switch rt := r.(type) {
-			case runtime.Error:
-				panic(r)
-			case error:
-				err = rt
-			case string:
-				err = errors.New(rt)
-			default:
-				panic(r)
-			}
-		}
-	}()
+func (in *injection) injectValue(srcValue, dstValue reflect.Value, path string) error {
+	if c, ok := in.converters[dstValue.Type().Elem()]; ok {
+		return in.convert(c, srcValue, dstValue.Elem(), path)
+	}
+	if src, ok := asSource(srcValue); ok {
+		if handled, err := in.injectSource(src, dstValue, path); handled {
+			return err
+		}
+	}
 
-	origSrcValue := srcValue
-	origDstValue := dstValue
-	dstValue = reflect.Indirect(dstValue)
-	srcValue = reflect.Indirect(reflect.ValueOf(srcValue.Interface()))
+	dstValue = dstValue.Elem()
+	if srcValue.IsValid() && !srcValue.CanInterface() {
+		return &UnexportedFieldError{
+			Path: path,
+		}
+	}
+	if srcValue.Kind() == reflect.Interface {
+		srcValue = srcValue.Elem()
+	}
+	if isNil(srcValue) {
+		return injectNil(srcValue, dstValue)
+	}
+	if handled, err := in.injectEnum(srcValue, dstValue, path); handled {
+		return err
+	}
+	if handled, err := unmarshalText(srcValue, dstValue, path); handled {
+		return err
+	}
+	if handled, err := in.injectVariant(srcValue, dstValue, path); handled {
+		return err
+	}
+	if key, ok := newVisit(srcValue, dstValue.Type()); ok {
+		if v, ok := in.sharedReference(key, dstValue); ok {
+			dstValue.Set(v)
+			return nil
+		}
+		if ref, ok := in.visiting[key]; ok {
+			return &CycleError{
+				Path: path,
+				Ref:  ref,
+			}
+		}
+		in.visiting[key] = path
+		defer delete(in.visiting, key)
+		if in.shared != nil {
+			in.shared[key] = dstValue.Addr()
+		}
+	}
+	if handled, err := in.copyDeep(srcValue, dstValue, path); handled {
+		return err
+	}
+	if handled, err := in.mergeInterface(srcValue, dstValue, path); handled {
+		return err
+	}
+	// Only values referenced by source pointers are addressed, so that
+	// other addressable source values, like struct fields, are copied.
+	srcPtr := srcValue.Kind() == reflect.Ptr
+	srcValue = reflect.Indirect(srcValue)
 	srcKind := srcValue.Kind()
 	dstKind := dstValue.Kind()
 
@@ -60,19 +156,20 @@ func inject(srcValue, dstValue reflect.Value, tagKey string) (err error) {
 		dstType := dstValue.Type()
 		dstTypeElem := dstType.Elem()
 		dstTypeElemKind := dstTypeElem.Kind()
-		if srcKind == reflect.Slice {
+		if srcKind == reflect.Slice || srcKind == reflect.Array {
 			srcLen := srcValue.Len()
-			dstValue.Set(reflect.MakeSlice(dstType, srcLen, srcValue.Cap()))
-			for i := 0; i < srcLen; i++ {
-				if err := inject(srcValue.Index(i), dstValue.Index(i), tagKey); err != nil {
-					return err
-				}
+			if err := in.checkLength(path, srcLen); err != nil {
+				return err
 			}
-			return nil
+			dstValue.Set(reflect.MakeSlice(dstType, srcLen, srcLen))
+			return in.injectElements(srcValue, dstValue, srcLen, path)
 		}
 		if dstTypeElemKind == reflect.Interface || srcKind == dstTypeElemKind {
+			if err := in.checkLength(path, 1); err != nil {
+				return err
+			}
 			dstValue.Set(reflect.MakeSlice(dstType, 1, 1))
-			return inject(srcValue, dstValue.Index(0), tagKey)
+			return in.inject(srcValue, dstValue.Index(0).Addr(), indexPath(path, 0))
 		}
 		return &InvalidTypeError{
 			TypeSrc: srcValue.Type(),
@@ -80,38 +177,56 @@ func inject(srcValue, dstValue reflect.Value, tagKey string) (err error) {
 		}
 	case reflect.Map:
 		dstType := dstValue.Type()
-		dstTypeElem := dstType.Elem()
 		switch srcKind {
 		case reflect.Map:
 			dstTypeKey := dstType.Key()
-			dstValue.Set(reflect.MakeMap(dstType))
-			for _, srcKey := range srcValue.MapKeys() {
-				dstKeyValue := reflect.New(dstTypeElem)
-				if err := inject(srcValue.MapIndex(srcKey), dstKeyValue, tagKey); err != nil {
-					return err
+			if err := in.checkMapEntries(path, srcValue.Len()); err != nil {
+				return err
+			}
+			keys := in.makeMap(dstValue)
+			if keys == nil {
+				if handled, err := in.copyMap(srcValue, dstValue, path); handled {
+					if err != nil {
+						return err
+					}
+					break
 				}
-				dstKey := reflect.New(dstTypeKey).Elem()
-				dstKey.Set(reflect.ValueOf(srcKey.Interface()))
-				dstValue.SetMapIndex(dstKey, reflect.Indirect(dstKeyValue))
 			}
-		case reflect.Struct:
-			dstValue.Set(reflect.MakeMap(dstType))
-			for i := 0; i < srcValue.NumField(); i++ {
-				dstKeyValue := reflect.New(dstTypeElem)
-				if err := inject(srcValue.Field(i), dstKeyValue, tagKey); err != nil {
+			var dstKeyValue reflect.Value
+			for _, srcKey := range srcValue.MapKeys() {
+				srcKeyPath := keyPath(path, srcKey)
+				dstKey := reflect.New(dstTypeKey)
+				if err := in.injectKey(srcKey, dstKey, srcKeyPath); err != nil {
 					return err
 				}
-				srcFieldType := srcValue.Type().Field(i)
-				keyName := keyNameFromTag(srcFieldType.Tag, tagKey)
-				if keyName == "-" {
-					continue
+				if keys.collision(dstValue, dstKey.Elem()) {
+					return &KeyCollisionError{
+						Path: srcKeyPath,
+						Key:  dstKey.Elem().Interface(),
+					}
+				}
+				dstKeyValue = in.mapValue(dstKeyValue, dstValue, dstKey.Elem())
+				if err := in.inject(srcValue.MapIndex(srcKey), dstKeyValue, srcKeyPath); err != nil {
+					return err
 				}
-				if keyName == "" {
-					keyName = srcFieldType.Name
+				dstValue.SetMapIndex(dstKey.Elem(), dstKeyValue.Elem())
+			}
+		case reflect.Struct:
+			if handled, err := in.injectGenerated(srcValue, dstValue); handled {
+				return err
+			}
+			if !isStringKey(dstType.Key()) {
+				return &InvalidTypeError{
+					TypeSrc: srcValue.Type(),
+					TypeDst: dstType,
 				}
-				dstKey := reflect.New(reflect.TypeOf(keyName)).Elem()
-				dstKey.SetString(keyName)
-				dstValue.SetMapIndex(dstKey, reflect.Indirect(dstKeyValue))
+			}
+			if err := in.checkMapEntries(path, srcValue.NumField()); err != nil {
+				return err
+			}
+			in.makeMap(dstValue)
+			if err := in.injectEntries(srcValue, dstValue, path, ""); err != nil {
+				return err
 			}
 		default:
 			return &InvalidTypeError{
@@ -122,82 +237,76 @@ func inject(srcValue, dstValue reflect.Value, tagKey string) (err error) {
 	case reflect.Struct:
 		switch srcKind {
 		case reflect.Map:
-			for i := 0; i < dstValue.NumField(); i++ {
-				dstKeyValue := reflect.New(dstValue.Field(i).Type())
-				dstFieldType := dstValue.Type().Field(i)
-				keyName := keyNameFromTag(dstFieldType.Tag, tagKey)
-				if keyName == "" {
-					keyName = dstFieldType.Name
+			if handled, err := in.injectGenerated(srcValue, dstValue); handled {
+				if err != nil {
+					return err
 				}
-				if keyName == "-" {
-					continue
+				break
+			}
+			srcTypeKey := srcValue.Type().Key()
+			if !isStringKey(srcTypeKey) {
+				return &InvalidTypeError{
+					TypeSrc: srcValue.Type(),
+					TypeDst: dstValue.Type(),
 				}
-				srcKey := reflect.New(reflect.TypeOf(keyName)).Elem()
-				srcKey.SetString(keyName)
-				srcMapValue := srcValue.MapIndex(srcKey)
-				if !srcMapValue.IsValid() {
-					if tagContains(dstFieldType.Tag, tagKey, "required") {
-						return &FieldRequiredError{
-							FieldName: keyName,
-						}
+			}
+			// Values of the most common map type are looked up directly and
+			// keys of other maps are set to the same value, as both
+			// reflect.Value.MapIndex and new keys allocate.
+			srcMap, isMap := srcValue.Interface().(map[string]interface{})
+			var srcKey reflect.Value
+			if !isMap {
+				srcKey = reflect.New(srcTypeKey).Elem()
+			}
+			err := in.injectFields(srcValue, dstValue, path, func(_ reflect.StructField, key string) (reflect.Value, bool) {
+				if isMap {
+					v, ok := srcMap[key]
+					if v == nil {
+						return reflect.Zero(srcValue.Type().Elem()), ok
 					}
-					continue
+					return reflect.ValueOf(v), ok
 				}
-				if err := inject(srcMapValue, dstKeyValue, tagKey); err != nil {
-					return err
-				}
-				dstValue.Field(i).Set(reflect.Indirect(dstKeyValue))
+				setStringKey(srcKey, key)
+				v := srcValue.MapIndex(srcKey)
+				return v, v.IsValid()
+			})
+			if err != nil {
+				return err
 			}
 		case reflect.Struct:
-			for i := 0; i < dstValue.NumField(); i++ {
-				dstField := dstValue.Field(i)
-				dstKeyValue := reflect.New(dstField.Type())
-				dstFieldType := dstValue.Type().Field(i)
-				fieldName := dstFieldType.Name
-				tagName := keyNameFromTag(dstFieldType.Tag, tagKey)
-				if tagName == "-" {
-					continue
+			if in.deepCopy && srcValue.Type() == dstValue.Type() {
+				return in.copyFields(srcValue, dstValue, path)
+			}
+			if handled, err := in.injectGenerated(srcValue, dstValue); handled {
+				if err != nil {
+					return err
 				}
-				srcStructValue := srcValue.FieldByName(fieldName)
-				if !srcStructValue.IsValid() {
-					if tagName != "" {
-						fieldName = tagName
-					}
-					srcStructValue = srcValue.FieldByName(fieldName)
-					if !srcStructValue.IsValid() {
-						srcStructValue = srcValue.FieldByName(strings.ToUpper(fieldName[:1]) + fieldName[1:])
-					}
-					if !srcStructValue.IsValid() {
-						if tagContains(dstFieldType.Tag, tagKey, "required") {
-							return &FieldRequiredError{
-								FieldName: fieldName,
-							}
-						}
-						continue
-					}
+				break
+			}
+			err := in.injectFields(srcValue, dstValue, path, func(field reflect.StructField, key string) (reflect.Value, bool) {
+				if !isExported(field) {
+					// Unexported source fields are never read, only
+					// reported as present.
+					_, ok := srcValue.Type().FieldByName(field.Name)
+					return reflect.Value{}, ok
 				}
-				srcStructValueTyped := srcStructValue
-				if srcStructValueTyped.CanAddr() && dstField.Kind() == srcStructValueTyped.Addr().Kind() {
-					srcStructValueTyped = srcStructValueTyped.Addr()
-					srcStructValue = srcStructValue.Addr()
+				v, ok := exportedFieldByName(srcValue, field.Name)
+				if !ok {
+					v, ok = exportedFieldByName(srcValue, key)
 				}
-				if !dstField.Type().AssignableTo(reflect.Indirect(srcStructValueTyped).Type()) {
-					fieldValue := dstValue.FieldByName(fieldName)
-					if fieldValue.IsValid() {
-						srcStructValueTyped = reflect.New(fieldValue.Type())
-						if err := inject(srcStructValue, srcStructValueTyped, tagKey); err != nil {
-							return err
-						}
-					}
+				if !ok {
+					v, ok = exportedFieldByName(srcValue, strings.ToUpper(key[:1])+key[1:])
 				}
-				if err := inject(srcStructValueTyped, dstKeyValue, tagKey); err != nil {
-					return err
+				if !ok {
+					v, ok = lookupPrefixed(srcValue, key, in.tagKey)
 				}
-				if srcStructValueTyped.Type().AssignableTo(dstField.Type()) {
-					dstField.Set(srcStructValueTyped)
-				} else {
-					dstField.Set(reflect.Indirect(srcStructValueTyped))
+				if ok && v.CanAddr() && v.Kind() != reflect.Ptr && field.Type.Kind() == reflect.Ptr {
+					v = v.Addr()
 				}
+				return v, ok
+			})
+			if err != nil {
+				return err
 			}
 		default:
 			return &InvalidTypeError{
@@ -205,75 +314,353 @@ func inject(srcValue, dstValue reflect.Value, tagKey string) (err error) {
 				TypeDst: dstValue.Type(),
 			}
 		}
+		if err := in.validate(dstValue, path); err != nil {
+			return err
+		}
 	case reflect.Array:
-		dstTypeElem := dstValue.Type().Elem()
-		if srcValue.Type().Elem().Kind() == dstTypeElem.Kind() {
-			srcValueTyped := srcValue
-			if !dstValue.Type().AssignableTo(srcValueTyped.Type()) {
-				srcValueTyped = reflect.Indirect(reflect.New(dstValue.Type()))
-				srcLen := srcValue.Len()
-				for i := 0; i < srcLen; i++ {
-					if err := inject(srcValue.Index(i), srcValueTyped.Index(i), tagKey); err != nil {
-						return err
-					}
-				}
+		dstType := dstValue.Type()
+		if srcKind != reflect.Array && srcKind != reflect.Slice || srcValue.Len() > dstValue.Len() {
+			return &InvalidTypeError{
+				TypeSrc: srcValue.Type(),
+				TypeDst: dstType,
 			}
-			dstValue.Set(srcValueTyped)
-			return
 		}
-		srcLen := srcValue.Len()
-		dstValue.Set(reflect.New(reflect.ArrayOf(srcLen, dstTypeElem)).Elem())
-		for i := 0; i < srcLen; i++ {
-			if err := inject(srcValue.Index(i), dstValue.Index(i), tagKey); err != nil {
-				return err
-			}
+		if !in.deepCopy && srcValue.Type().AssignableTo(dstType) {
+			dstValue.Set(srcValue)
+			return in.validateAssigned(dstValue, path)
+		}
+		if err := in.checkLength(path, srcValue.Len()); err != nil {
+			return err
+		}
+		dstValue.Set(reflect.Zero(dstType))
+		if err := in.injectElements(srcValue, dstValue, srcValue.Len(), path); err != nil {
+			return err
 		}
 	default:
-		if srcValue.CanAddr() && dstKind == srcValue.Addr().Kind() {
+		// Pointers are always allocated when values are deep copied or
+		// layers are merged, and existing values are injected into when
+		// layers are merged.
+		if in.merge && dstKind == reflect.Ptr && !dstValue.IsNil() {
+			return in.inject(srcValue, dstValue, path)
+		}
+		deepPtr := (in.deepCopy || in.merge) && dstKind == reflect.Ptr
+		if !deepPtr && srcPtr && srcKind != reflect.Ptr && (dstKind == reflect.Ptr ||
+			dstKind == reflect.Interface && !srcValue.Type().AssignableTo(dstValue.Type())) {
 			srcValue = srcValue.Addr()
-			srcKind = srcValue.Kind()
 		}
-		if dstKind != srcKind && dstKind != reflect.Interface {
-			if origDstValue.Type() == origSrcValue.Type() && origDstValue.Type().AssignableTo(origSrcValue.Type()) {
-				origDstValue.Set(origSrcValue)
-				return
+		if !deepPtr && srcValue.Type().AssignableTo(dstValue.Type()) {
+			dstValue.Set(srcValue)
+			return in.validateAssigned(dstValue, path)
+		}
+		if dstKind == reflect.Ptr {
+			p := reflect.New(dstValue.Type().Elem())
+			dstValue.Set(p)
+			return in.inject(srcValue, p, path)
+		}
+		if in.converts() && convertBasic(srcValue, dstValue) {
+			return nil
+		}
+		return &InvalidTypeError{
+			TypeSrc: srcValue.Type(),
+			TypeDst: dstValue.Type(),
+		}
+	}
+	return nil
+}
+
+// injectEntries injects exported fields of the source struct into the
+// destination map, with key names prefixed by the prefix. Fields with the
+// prefix tag option that are structs are flattened into the same map.
+func (in *injection) injectEntries(srcValue, dstValue reflect.Value, path, prefix string) error {
+	dstType := dstValue.Type()
+	var dstKey, dstKeyValue reflect.Value
+	for i := 0; i < srcValue.NumField(); i++ {
+		srcFieldType := srcValue.Type().Field(i)
+		if !isExported(srcFieldType) {
+			continue
+		}
+		keyName := keyNameFromTag(srcFieldType.Tag, in.tagKey)
+		if keyName == "-" {
+			continue
+		}
+		if keyName == "" {
+			keyName = srcFieldType.Name
+		}
+		srcFieldPath := fieldPath(path, srcFieldType.Name)
+		if p, ok := tagOption(srcFieldType.Tag, in.tagKey, "prefix"); ok {
+			v := srcValue.Field(i)
+			if v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.Struct {
+				if v.IsNil() {
+					continue
+				}
+				v = v.Elem()
 			}
-			return &InvalidTypeError{
-				TypeSrc: origSrcValue.Type(),
-				TypeDst: origDstValue.Type(),
+			if v.Kind() == reflect.Struct {
+				if err := in.injectEntries(v, dstValue, srcFieldPath, prefix+p); err != nil {
+					return err
+				}
+				continue
 			}
 		}
-		if srcValue.Type().AssignableTo(dstValue.Type()) {
-			dstValue.Set(srcValue)
-		} else {
-			dstValue.Set(reflect.Indirect(srcValue))
+		if !dstKey.IsValid() {
+			dstKey = reflect.New(dstType.Key()).Elem()
+		}
+		setStringKey(dstKey, prefix+keyName)
+		dstKeyValue = in.mapValue(dstKeyValue, dstValue, dstKey)
+		v := srcValue.Field(i)
+		switch {
+		case isSecret(srcFieldType, in.tagKey):
+			maskValue(dstKeyValue.Elem(), in.secretMask)
+		case in.enumName(v, dstKeyValue.Elem(), srcFieldType):
+		case holdsInterfaces(dstKeyValue.Elem().Type(), nil) && in.containsSecrets(v):
+			// Values that are assigned to interfaces as they are must not
+			// expose their secret fields.
+			c, err := in.redactedCopy(v)
+			if err != nil {
+				return err
+			}
+			if err := in.inject(c, dstKeyValue, srcFieldPath); err != nil {
+				return err
+			}
+		default:
+			if err := in.inject(v, dstKeyValue, srcFieldPath); err != nil {
+				return err
+			}
 		}
+		dstValue.SetMapIndex(dstKey, dstKeyValue.Elem())
 	}
 	return nil
 }
 
-func keyNameFromTag(structTag reflect.StructTag, tagKey string) (keyName string) {
-	if tag := structTag.Get(tagKey); tag != "" {
-		if strings.Contains(tag, ",") {
-			keyName = strings.Split(tag, ",")[0]
+// isNil returns true if the value is invalid or a nil interface or pointer.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// injectNil sets the destination to its zero value if it can be nil, or
+// returns an error otherwise.
+func injectNil(srcValue, dstValue reflect.Value) error {
+	switch dstValue.Kind() {
+	case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map:
+		dstValue.Set(reflect.Zero(dstValue.Type()))
+		return nil
+	}
+	var srcType reflect.Type
+	if srcValue.IsValid() && srcValue.Kind() != reflect.Interface {
+		srcType = srcValue.Type()
+	}
+	return &InvalidTypeError{
+		TypeSrc: srcType,
+		TypeDst: dstValue.Type(),
+	}
+}
+
+// stringKey returns the string as a value of the map key type, if the
+// string can be used as a key of that type.
+func stringKey(key string, keyType reflect.Type) (reflect.Value, bool) {
+	if !isStringKey(keyType) {
+		return reflect.Value{}, false
+	}
+	v := reflect.ValueOf(key)
+	if keyType.Kind() == reflect.String {
+		return v.Convert(keyType), true
+	}
+	return v, true
+}
+
+// setStringKey sets the map key to the string without allocating it for
+// keys of string types.
+func setStringKey(k reflect.Value, key string) {
+	if k.Kind() == reflect.String {
+		k.SetString(key)
+		return
+	}
+	k.Set(reflect.ValueOf(key))
+}
+
+// isStringKey returns true if strings can be used as keys of maps with the
+// key type.
+func isStringKey(keyType reflect.Type) bool {
+	return keyType.Kind() == reflect.String || stringType.AssignableTo(keyType)
+}
+
+// injectFields injects values returned by the lookup function into fields of
+// the destination struct. The lookup function is called with the field and
+// its key name for every field that is not skipped by the struct tag. Fields
+// with the from tag option are looked up by paths in the source value first,
+// and by their key name if none of the paths is present, so that values of
+// the same type are injected, too.
+// Fields with the prefix tag option are looked up with keys of a flat source
+// value if the source does not have a value for their key name.
+// Unexported fields are skipped, or an error is returned if the source has a
+// value for them and the Injector is configured to do so. Fields with the
+// shallow tag option are assigned the source value directly if its type is
+// assignable. String transformations from tag options are applied to
+// injected fields before they are validated.
+func (in *injection) injectFields(srcValue, dstValue reflect.Value, path string, lookup func(field reflect.StructField, key string) (reflect.Value, bool)) error {
+	for i := 0; i < dstValue.NumField(); i++ {
+		dstField := dstValue.Field(i)
+		dstFieldType := dstValue.Type().Field(i)
+		keyName := keyNameFromTag(dstFieldType.Tag, in.tagKey)
+		if keyName == "" {
+			keyName = dstFieldType.Name
+		}
+		if keyName == "-" {
+			continue
+		}
+		var srcFieldValue reflect.Value
+		var ok bool
+		if from, isFrom := tagOption(dstFieldType.Tag, in.tagKey, "from"); isFrom {
+			srcFieldValue, ok = lookupFrom(srcValue, from)
+			if !ok {
+				srcFieldValue, ok = lookup(dstFieldType, keyName)
+			}
+		} else if prefix, isPrefix := tagOption(dstFieldType.Tag, in.tagKey, "prefix"); isPrefix {
+			// Nested sources have the value under the key of the field,
+			// and flat sources have prefixed keys.
+			srcFieldValue, ok = lookup(dstFieldType, keyName)
+			if !ok {
+				src := newPrefixSource(srcValue, prefix)
+				srcFieldValue, ok = reflect.ValueOf(src), len(src.Keys()) > 0
+			}
 		} else {
-			keyName = tag
+			srcFieldValue, ok = lookup(dstFieldType, keyName)
+		}
+		if !isExported(dstFieldType) {
+			if ok && in.unexportedFieldError {
+				return &UnexportedFieldError{
+					Path: fieldPath(path, dstFieldType.Name),
+				}
+			}
+			continue
+		}
+		dstFieldPath := fieldPath(path, dstFieldType.Name)
+		if (!ok || in.checks != nil) && tagContains(dstFieldType.Tag, in.tagKey, "required") {
+			if err := in.checkRequired(dstFieldPath, keyName, ok); err != nil {
+				return err
+			}
+		}
+		if !ok {
+			continue
 		}
+		srcFieldValue, skip, err := in.callFieldHooks(dstFieldPath, srcFieldValue, dstField.Addr())
+		if err != nil {
+			return err
+		}
+		if skip {
+			continue
+		}
+		enumOption, isEnum := tagOption(dstFieldType.Tag, in.tagKey, "enum")
+		switch {
+		case isEnum:
+			if err := in.injectEnumField(srcFieldValue, dstField, enumOption, dstFieldPath); err != nil {
+				return in.fieldError(dstFieldType, err)
+			}
+			in.record(srcFieldValue, dstField, dstFieldPath)
+		case tagContains(dstFieldType.Tag, in.tagKey, "shallow") && srcFieldValue.IsValid() &&
+			srcFieldValue.CanInterface() && srcFieldValue.Type().AssignableTo(dstField.Type()):
+			dstField.Set(srcFieldValue)
+			in.record(srcFieldValue, dstField, dstFieldPath)
+		case in.setDirect(srcFieldValue, dstField):
+			if err := in.checkContext(dstFieldPath); err != nil {
+				return err
+			}
+		default:
+			if err := in.inject(srcFieldValue, dstField.Addr(), dstFieldPath); err != nil {
+				return in.fieldError(dstFieldType, err)
+			}
+		}
+		transformField(dstField, dstFieldType.Tag, in.tagKey)
+		if err := validateField(dstField, dstFieldType.Tag, in.tagKey, dstFieldPath); err != nil {
+			return in.fieldError(dstFieldType, err)
+		}
+	}
+	return nil
+}
+
+// exportedFieldByName returns the exported struct field with the given name.
+func exportedFieldByName(v reflect.Value, name string) (reflect.Value, bool) {
+	f, ok := v.Type().FieldByName(name)
+	if !ok || !isExported(f) {
+		return reflect.Value{}, false
+	}
+	// Fields promoted through nil embedded pointers are not present.
+	fv, err := v.FieldByIndexErr(f.Index)
+	if err != nil {
+		return reflect.Value{}, false
+	}
+	return fv, true
+}
+
+func isExported(f reflect.StructField) bool {
+	return f.PkgPath == ""
+}
+
+func keyNameFromTag(structTag reflect.StructTag, tagKey string) (keyName string) {
+	keyName, _, _ = strings.Cut(structTag.Get(tagKey), ",")
+	if strings.Contains(keyName, "=") {
+		// An option in the name=value form, like from, is given in place
+		// of the key name.
+		return ""
 	}
 	return keyName
 }
 
+// tagOptions returns comma separated options of the struct tag, without the
+// key name. An option in the name=value form may be given in place of the key
+// name, and it is included.
+func tagOptions(structTag reflect.StructTag, tagKey string) string {
+	tag := structTag.Get(tagKey)
+	keyName, options, _ := strings.Cut(tag, ",")
+	if strings.Contains(keyName, "=") {
+		return tag
+	}
+	return options
+}
+
 func tagContains(structTag reflect.StructTag, tagKey, tagValue string) bool {
-	if tag := structTag.Get(tagKey); tag != "" {
-		l := strings.Split(tag, ",")
-		if len(l) < 2 {
-			return false
-		}
-		for _, e := range l[1:] {
-			if e == tagValue {
-				return true
-			}
+	options := tagOptions(structTag, tagKey)
+	for options != "" {
+		var o string
+		o, options, _ = strings.Cut(options, ",")
+		if o == tagValue {
+			return true
 		}
 	}
 	return false
 }
+
+// tagOption returns the value of the struct tag option in the name=value
+// form. The option may also be given in place of the key name.
+func tagOption(structTag reflect.StructTag, tagKey, name string) (value string, ok bool) {
+	tag := structTag.Get(tagKey)
+	for tag != "" {
+		var o string
+		o, tag, _ = strings.Cut(tag, ",")
+		if len(o) > len(name) && o[len(name)] == '=' && o[:len(name)] == name {
+			return o[len(name)+1:], true
+		}
+	}
+	return "", false
+}
+
+func fieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func indexPath(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+func keyPath(path string, key reflect.Value) string {
+	return path + "[" + fmt.Sprint(key.Interface()) + "]"
+}
@@ -640,3 +640,118 @@ func TestInvalidRequiredFieldErrorFromStruct(t *testing.T) {
 		t.Errorf("Expected FieldRequiredError FieldName Test2, but got %#v", terr.FieldName)
 	}
 }
+
+func TestInvalidInjectErrorNilDestination(t *testing.T) {
+	err := Inject("", nil)
+	if _, ok := err.(*InvalidInjectError); !ok {
+		t.Errorf("Expected InvalidInjectError, but got %#v", err)
+	}
+}
+
+func TestInjectNil(t *testing.T) {
+	s := map[string]interface{}{
+		"Any":   nil,
+		"Slice": nil,
+		"Map":   nil,
+		"Ptr":   (*big.Int)(nil),
+	}
+	d := struct {
+		Any   interface{}
+		Slice []string
+		Map   map[string]string
+		Ptr   *big.Int
+	}{
+		Any:   "value",
+		Slice: []string{"value"},
+		Map:   map[string]string{"key": "value"},
+		Ptr:   big.NewInt(42),
+	}
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Any != nil || d.Slice != nil || d.Map != nil || d.Ptr != nil {
+		t.Errorf("destination %#v is not set to zero values", d)
+	}
+}
+
+func TestInvalidTypeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  interface{}
+		dst  interface{}
+	}{
+		{
+			name: "nil to int",
+			src:  nil,
+			dst:  new(int),
+		},
+		{
+			name: "map with int keys to struct",
+			src:  map[int]string{1: "one"},
+			dst:  new(Struct1),
+		},
+		{
+			name: "struct to map with int keys",
+			src:  Struct2{},
+			dst:  new(map[int]interface{}),
+		},
+		{
+			name: "map key type",
+			src:  map[interface{}]string{[2]int{1, 2}: "one"},
+			dst:  new(map[string]string),
+		},
+		{
+			name: "longer array",
+			src:  [3]string{"a", "b", "c"},
+			dst:  new([2]string),
+		},
+		{
+			name: "string to array",
+			src:  "test",
+			dst:  new([2]string),
+		},
+		{
+			name: "unimplemented interface",
+			src:  "test",
+			dst:  new(interface{ Read([]byte) (int, error) }),
+		},
+		{
+			name: "same kind",
+			src:  int64(1),
+			dst:  new(int),
+		},
+		{
+			name: "string to int",
+			src:  "1",
+			dst:  new(int),
+		},
+		{
+			name: "integer overflow",
+			src:  300,
+			dst:  new(int8),
+		},
+		{
+			name: "fractional float to int",
+			src:  1.5,
+			dst:  new(int),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Inject(tc.src, tc.dst)
+			if _, ok := err.(*InvalidTypeError); !ok {
+				t.Errorf("Expected InvalidTypeError, but got %#v", err)
+			}
+		})
+	}
+}
+
+func TestInjectSliceOfPointers(t *testing.T) {
+	s := []*big.Int{big.NewInt(1), nil, big.NewInt(3)}
+	var d []*big.Int
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, s) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, s)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// Injector injects fields of source objects into destination objects. Its
+// behavior is configured with options passed to NewInjector. Injector is safe
+// for concurrent use.
+type Injector struct {
+	tagKey               string
+	fieldHooks           []FieldHook
+	unexportedFieldError bool
+	sharedReferences     bool
+	deepCopy             bool
+	maxDepth             int
+	maxLength            int
+	maxMapEntries        int
+	maxElements          int
+	converters           map[reflect.Type]Converter
+	valueConversion      bool
+	variantKey           string
+	variantsMu           sync.RWMutex
+	variants             map[reflect.Type]map[string]reflect.Type
+	parallelism          int
+	parallelThreshold    int
+	secretMask           string
+	enumFold             bool
+	enumsMu              sync.RWMutex
+	enums                map[reflect.Type]*enum
+}
+
+// Option sets optional parameters for Injector.
+type Option func(*Injector)
+
+// WithTagKey sets the key for Go struct tags that Injector will check. The
+// default is the value of DefaultTagKey at the time of NewInjector call.
+func WithTagKey(tagKey string) Option {
+	return func(in *Injector) {
+		in.tagKey = tagKey
+	}
+}
+
+// WithFieldHook adds a function that is called before every destination
+// struct field is injected. Hooks are called in the order they are added.
+func WithFieldHook(hook FieldHook) Option {
+	return func(in *Injector) {
+		in.fieldHooks = append(in.fieldHooks, hook)
+	}
+}
+
+// WithUnexportedFieldError configures Injector to return UnexportedFieldError
+// when the source has a value for an unexported destination struct field,
+// instead of skipping it.
+func WithUnexportedFieldError() Option {
+	return func(in *Injector) {
+		in.unexportedFieldError = true
+	}
+}
+
+// WithSharedReferences configures Injector to preserve sharing of pointers,
+// maps and slices from the source in the destination. Every source reference
+// that is injected more than once into destinations of the same type results
+// in the same destination pointer, map or slice. Cycles in the source are then
+// recreated in the destination instead of returning CycleError, if the
+// destination types can hold them.
+func WithSharedReferences() Option {
+	return func(in *Injector) {
+		in.sharedReferences = true
+	}
+}
+
+// WithMaxDepth limits the depth of nested values in the source. Zero value
+// means no limit.
+func WithMaxDepth(n int) Option {
+	return func(in *Injector) {
+		in.maxDepth = n
+	}
+}
+
+// WithMaxLength limits the length of every slice and array in the source.
+// Zero value means no limit.
+func WithMaxLength(n int) Option {
+	return func(in *Injector) {
+		in.maxLength = n
+	}
+}
+
+// WithMaxMapEntries limits the number of entries of every map that is
+// injected into the destination. Zero value means no limit.
+func WithMaxMapEntries(n int) Option {
+	return func(in *Injector) {
+		in.maxMapEntries = n
+	}
+}
+
+// WithMaxElements limits the total number of slice and array elements and
+// map entries in a single injection. Zero value means no limit.
+func WithMaxElements(n int) Option {
+	return func(in *Injector) {
+		in.maxElements = n
+	}
+}
+
+// WithConverter registers a Converter for the type of the provided value.
+// Converters are called for every destination of that type instead of
+// injecting the source value directly. For interface types, a nil pointer to
+// the interface should be provided.
+func WithConverter(dst interface{}, c Converter) Option {
+	return func(in *Injector) {
+		if in.converters == nil {
+			in.converters = make(map[reflect.Type]Converter)
+		}
+		in.converters[typeOf(dst)] = c
+	}
+}
+
+// NewInjector creates a new Injector with provided options.
+func NewInjector(opts ...Option) *Injector {
+	in := &Injector{
+		tagKey:            DefaultTagKey,
+		variantKey:        DefaultVariantKey,
+		parallelThreshold: DefaultParallelThreshold,
+		secretMask:        DefaultSecretMask,
+	}
+	for _, opt := range opts {
+		opt(in)
+	}
+	return in
+}
+
+// injection holds the state of a single injection.
+type injection struct {
+	*Injector
+	ctx context.Context
+	// done is the done channel of the context, nil if the context can not
+	// be canceled.
+	done <-chan struct{}
+	// steps is the number of injected values, used to check the context
+	// periodically.
+	steps int
+	// visiting holds paths of source references that are currently being
+	// injected, to detect cycles.
+	visiting map[visit]string
+	// shared holds pointers to destinations of all injected source
+	// references when the sharing of references is preserved.
+	shared map[visit]reflect.Value
+	// depth is the current depth of nested values.
+	depth int
+	// elements is the number of slice and array elements and map entries
+	// that are injected so far.
+	elements int
+	// dryRun is true if assignments are recorded by DryRun.
+	dryRun      bool
+	assignments []Assignment
+	// result holds paths of set destination values with the label of the
+	// source, if they are tracked.
+	result *Result
+	label  string
+	// injectingKey is true while a map key is injected, as keys are not
+	// recorded.
+	injectingKey bool
+	// merge is true when layers are merged into the destination.
+	merge bool
+	// converting is true while values of sources returned by EnvSource and
+	// FlagSource are injected, as they are converted as map keys are.
+	converting bool
+	// checks holds required fields and validators that are checked after
+	// all layers are injected, nil for other injections.
+	checks *layerChecks
+}
+
+// injectionPool holds injection states with allocated maps for reuse, as
+// most injections are too small to justify allocating them every time.
+var injectionPool = sync.Pool{
+	New: func() interface{} {
+		return &injection{
+			visiting: make(map[visit]string),
+		}
+	},
+}
+
+// newInjection returns the state for a new injection that should be
+// released when the injection is done.
+func (in *Injector) newInjection(ctx context.Context) *injection {
+	i := injectionPool.Get().(*injection)
+	i.Injector = in
+	i.ctx = ctx
+	i.done = ctx.Done()
+	if in.sharedReferences {
+		i.shared = make(map[visit]reflect.Value)
+	}
+	return i
+}
+
+// release resets the injection state and puts it back to the pool. It must
+// not be used after that.
+func (in *injection) release() {
+	visiting := in.visiting
+	clear(visiting)
+	*in = injection{
+		visiting: visiting,
+	}
+	injectionPool.Put(in)
+}
+
+// typeOf returns the type of the value, or the interface type if the value
+// is a nil pointer to an interface.
+func typeOf(v interface{}) reflect.Type {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Interface {
+		return t.Elem()
+	}
+	return t
+}
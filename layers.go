@@ -0,0 +1,396 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"context"
+	"flag"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Layer is a named source for InjectLayers. The source can be any value
+// that Inject accepts, like a struct, a map, or a Source returned by
+// EnvSource or FlagSource.
+type Layer struct {
+	Name   string
+	Source interface{}
+}
+
+// InjectLayers injects sources of all layers into the destination in the
+// given order. It is the same as calling the InjectLayers method of Injector
+// with default options.
+func InjectLayers(dst interface{}, layers ...Layer) (*Result, error) {
+	return NewInjector().InjectLayers(dst, layers...)
+}
+
+// InjectLayers injects sources of all layers into the destination in the
+// given order, merging them, so that later layers override values of
+// earlier layers only for keys that they contain. Maps are merged by keys,
+// values referenced by pointers are merged into existing values, and slices
+// and arrays are replaced. The returned Result records the name of the layer
+// that every destination value is set from, and it holds values set before
+// the error if one is returned. Required fields and Validate methods are
+// checked once, after all layers are injected, so that a required field may
+// be provided by any layer.
+func (in *Injector) InjectLayers(dst interface{}, layers ...Layer) (*Result, error) {
+	dstValue := reflect.ValueOf(dst)
+	if !dstValue.IsValid() {
+		return nil, &InvalidInjectError{}
+	}
+	if dstValue.Kind() != reflect.Ptr || dstValue.IsNil() {
+		return nil, &InvalidInjectError{dstValue.Type()}
+	}
+	result := new(Result)
+	checks := &layerChecks{
+		set:        make(map[string]struct{}),
+		validators: make(map[string]struct{}),
+	}
+	for _, l := range layers {
+		i := in.newInjection(context.Background())
+		i.merge = true
+		i.result = result
+		i.label = l.Name
+		i.checks = checks
+		err := i.inject(reflect.ValueOf(l.Source), dstValue, "")
+		i.release()
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, checks.check(dstValue.Elem(), in.tagKey)
+}
+
+// layerChecks holds required fields and validators of structs that are
+// checked after all layers are injected.
+type layerChecks struct {
+	// required holds required fields that are missing in some layer, in
+	// the order in which they are found.
+	required []requiredField
+	// set holds paths of required fields that are set by some layer.
+	set map[string]struct{}
+	// validators holds paths of injected structs.
+	validators map[string]struct{}
+}
+
+type requiredField struct {
+	path    string
+	keyName string
+}
+
+// check returns the error for the first required field that is not set by
+// any layer, or the first error returned by Validate methods of injected
+// structs.
+func (c *layerChecks) check(v reflect.Value, tagKey string) error {
+	for _, f := range c.required {
+		if _, ok := c.set[f.path]; !ok {
+			return &FieldRequiredError{
+				FieldName: f.keyName,
+			}
+		}
+	}
+	if len(c.validators) == 0 {
+		return nil
+	}
+	return walkValidators(v, "", tagKey, make(map[visit]struct{}), func(v reflect.Value, path string) error {
+		if _, ok := c.validators[path]; !ok {
+			return nil
+		}
+		return callValidator(v, path)
+	})
+}
+
+// drop removes required fields under the path, as the value at the path is
+// replaced.
+func (c *layerChecks) drop(path string) {
+	c.required = slices.DeleteFunc(c.required, func(f requiredField) bool {
+		return isUnder(f.path, path)
+	})
+	for p := range c.set {
+		if isUnder(p, path) {
+			delete(c.set, p)
+		}
+	}
+}
+
+// checkRequired returns FieldRequiredError if the required field is not
+// found in the source. When layers are injected, the check is deferred until
+// all layers are injected.
+func (in *injection) checkRequired(path, keyName string, found bool) error {
+	if in.checks == nil {
+		if found {
+			return nil
+		}
+		return &FieldRequiredError{
+			FieldName: keyName,
+		}
+	}
+	if found {
+		in.checks.set[path] = struct{}{}
+	} else {
+		in.checks.required = append(in.checks.required, requiredField{
+			path:    path,
+			keyName: keyName,
+		})
+	}
+	return nil
+}
+
+// validate calls the Validate method of the injected struct value. When
+// layers are injected, the method is called after all layers are injected.
+func (in *injection) validate(v reflect.Value, path string) error {
+	if in.checks != nil {
+		in.checks.validators[path] = struct{}{}
+		return nil
+	}
+	return callValidator(v, path)
+}
+
+// replace removes recorded paths and required fields under the path if the
+// destination value is replaced by the source value when layers are merged,
+// so that only values of the last layer that sets it are kept.
+func (in *injection) replace(srcValue, dstValue reflect.Value, path string) {
+	if !in.merge {
+		return
+	}
+	if srcValue.Kind() == reflect.Interface {
+		srcValue = srcValue.Elem()
+	}
+	switch dstValue.Kind() {
+	case reflect.Slice, reflect.Array:
+	case reflect.Interface:
+		if isNil(srcValue) || reflect.Indirect(srcValue).Kind() == reflect.Map {
+			return
+		}
+	case reflect.Ptr, reflect.Map:
+		if !isNil(srcValue) {
+			return
+		}
+	default:
+		return
+	}
+	if in.result != nil {
+		in.result.drop(path)
+	}
+	if in.checks != nil {
+		in.checks.drop(path)
+	}
+}
+
+// isUnder returns true if the path is the path of a value nested in the
+// value at the parent path.
+func isUnder(path, parent string) bool {
+	if parent == "" {
+		return path != ""
+	}
+	return len(path) > len(parent) && strings.HasPrefix(path, parent) &&
+		(path[len(parent)] == '.' || path[len(parent)] == '[')
+}
+
+// mapKeys holds keys injected into an existing map when layers are merged,
+// to detect collisions only between keys from the same source.
+type mapKeys map[interface{}]struct{}
+
+// makeMap sets a new map to the destination, unless layers are merged into
+// an existing map. It returns keys to detect collisions with.
+func (in *injection) makeMap(dstValue reflect.Value) mapKeys {
+	if in.merge && !dstValue.IsNil() {
+		return make(mapKeys)
+	}
+	dstValue.Set(reflect.MakeMap(dstValue.Type()))
+	return nil
+}
+
+// collision returns true if the key is already injected into the map.
+func (k mapKeys) collision(m, key reflect.Value) bool {
+	if k == nil {
+		return m.MapIndex(key).IsValid()
+	}
+	v := key.Interface()
+	if _, ok := k[v]; ok {
+		return true
+	}
+	k[v] = struct{}{}
+	return false
+}
+
+// mapValue returns a pointer to a new value for the map key, set to the
+// existing value when layers are merged. The pointer v from the previous call
+// is reused if it is valid, unless references are shared, as they point to
+// their destinations.
+func (in *injection) mapValue(v, m, key reflect.Value) reflect.Value {
+	if v.IsValid() && in.shared == nil {
+		v.Elem().SetZero()
+	} else {
+		v = reflect.New(m.Type().Elem())
+	}
+	if in.merge {
+		if e := m.MapIndex(key); e.IsValid() {
+			v.Elem().Set(e)
+		}
+	}
+	return v
+}
+
+// mergeInterface merges the source map into a copy of the map that the
+// interface destination holds when layers are merged, so that maps from
+// sources of earlier layers are not changed. It returns false for all other
+// values.
+func (in *injection) mergeInterface(srcValue, dstValue reflect.Value, path string) (handled bool, err error) {
+	if !in.merge || dstValue.Kind() != reflect.Interface || reflect.Indirect(srcValue).Kind() != reflect.Map {
+		return false, nil
+	}
+	t := reflect.Indirect(srcValue).Type()
+	e := dstValue.Elem()
+	if e.Kind() == reflect.Map {
+		t = e.Type()
+	}
+	if !t.AssignableTo(dstValue.Type()) {
+		return false, nil
+	}
+	m := reflect.MakeMap(t)
+	if e.Kind() == reflect.Map {
+		iter := e.MapRange()
+		for iter.Next() {
+			m.SetMapIndex(iter.Key(), iter.Value())
+		}
+	}
+	v := reflect.New(t)
+	v.Elem().Set(m)
+	if err := in.inject(srcValue, v, path); err != nil {
+		return true, err
+	}
+	dstValue.Set(v.Elem())
+	return true, nil
+}
+
+// EnvSource returns a Source with environment variables that have the
+// prefix. Keys are lowercased names of variables without the prefix and they
+// are matched case-insensitively. Keys of nested values are separated by
+// underscores, so that the APP_DATABASE_HOST variable is injected into the
+// host field of the database field with the APP_ prefix, and the
+// APP_LABELS_ENV variable into the env key of the labels map field. Values
+// are converted to destination types in the same way as map keys.
+func EnvSource(prefix string) Source {
+	values := make(map[string]interface{})
+	for _, e := range os.Environ() {
+		name, value, ok := strings.Cut(e, "=")
+		if !ok || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		values[strings.ToLower(name[len(prefix):])] = value
+	}
+	return treeSource{
+		values: values,
+		sep:    "_",
+		key:    strings.ToLower,
+	}.withKeys()
+}
+
+// FlagSource returns a Source with values of flags that are set on the flag
+// set. Keys of nested values are separated by dots, so that the
+// database.host flag is injected into the host field of the database field.
+// Values of flags that implement flag.Getter are provided as returned by
+// their Get methods, and of other flags as strings. Values are converted to
+// destination types in the same way as map keys.
+func FlagSource(fs *flag.FlagSet) Source {
+	values := make(map[string]interface{})
+	fs.Visit(func(f *flag.Flag) {
+		if g, ok := f.Value.(flag.Getter); ok {
+			values[f.Name] = g.Get()
+			return
+		}
+		values[f.Name] = f.Value.String()
+	})
+	return treeSource{
+		values: values,
+		sep:    ".",
+	}.withKeys()
+}
+
+// treeSource is a Source of values with names that are keys of nested values
+// separated by the separator.
+type treeSource struct {
+	values map[string]interface{}
+	prefix string
+	sep    string
+	// key normalizes keys, if not nil.
+	key func(string) string
+	// keys holds keys of nested values under the prefix.
+	keys []string
+}
+
+// withKeys returns the source with keys of nested values under its prefix,
+// so that they are not searched for on every Keys and Index call.
+func (s treeSource) withKeys() treeSource {
+	seen := make(map[string]struct{})
+	keys := make([]string, 0)
+	for name := range s.values {
+		if !strings.HasPrefix(name, s.prefix) || len(name) == len(s.prefix) {
+			continue
+		}
+		k, _, _ := strings.Cut(name[len(s.prefix):], s.sep)
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		keys = append(keys, k)
+	}
+	sortKeys(keys)
+	s.keys = keys
+	return s
+}
+
+// sortKeys sorts keys as numbers if all of them are integers, like indexes
+// of slice elements, or as strings otherwise.
+func sortKeys(keys []string) {
+	numbers := make(map[string]int, len(keys))
+	for _, k := range keys {
+		n, err := strconv.Atoi(k)
+		if err != nil {
+			sort.Strings(keys)
+			return
+		}
+		numbers[k] = n
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return numbers[keys[i]] < numbers[keys[j]]
+	})
+}
+
+func (s treeSource) Keys() []string {
+	return s.keys
+}
+
+func (s treeSource) Lookup(key string) (interface{}, bool) {
+	if s.key != nil {
+		key = s.key(key)
+	}
+	name := s.prefix + key
+	if v, ok := s.values[name]; ok {
+		return v, true
+	}
+	child := s
+	child.prefix = name + s.sep
+	child = child.withKeys()
+	if len(child.Keys()) == 0 {
+		return nil, false
+	}
+	return child, true
+}
+
+func (s treeSource) Len() int {
+	return len(s.keys)
+}
+
+func (s treeSource) Index(i int) interface{} {
+	v, _ := s.Lookup(s.keys[i])
+	return v
+}
@@ -0,0 +1,287 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"errors"
+	"flag"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type layeredDatabase struct {
+	Host string `taint:"host"`
+	Port int    `taint:"port"`
+}
+
+type layeredConfig struct {
+	Name     string            `taint:"name"`
+	Debug    bool              `taint:"debug"`
+	Labels   map[string]string `taint:"labels"`
+	Hosts    []string          `taint:"hosts"`
+	Database *layeredDatabase  `taint:"database"`
+}
+
+func TestInjectLayers(t *testing.T) {
+	t.Setenv("LAYERS_TEST_DATABASE_PORT", "6432")
+	t.Setenv("LAYERS_TEST_LABELS_ENV", "prod")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Bool("debug", false, "")
+	fs.String("database.host", "", "")
+	fs.String("name", "", "")
+	if err := fs.Parse([]string{"-debug", "-database.host", "db.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	defaults := layeredConfig{
+		Name:  "app",
+		Hosts: []string{"a", "b"},
+		Database: &layeredDatabase{
+			Host: "localhost",
+			Port: 5432,
+		},
+	}
+	var c layeredConfig
+	r, err := InjectLayers(&c,
+		Layer{
+			Name:   "defaults",
+			Source: defaults,
+		},
+		Layer{
+			Name: "file",
+			Source: map[string]interface{}{
+				"labels": map[string]interface{}{
+					"env":  "dev",
+					"team": "core",
+				},
+				"hosts": []interface{}{"c"},
+			},
+		},
+		Layer{
+			Name:   "env",
+			Source: EnvSource("LAYERS_TEST_"),
+		},
+		Layer{
+			Name:   "flags",
+			Source: FlagSource(fs),
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := layeredConfig{
+		Name:  "app",
+		Debug: true,
+		Labels: map[string]string{
+			"env":  "prod",
+			"team": "core",
+		},
+		Hosts: []string{"c"},
+		Database: &layeredDatabase{
+			Host: "db.example.com",
+			Port: 6432,
+		},
+	}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", c, c, expected)
+	}
+	if defaults.Database.Port != 5432 {
+		t.Errorf("layer source is changed to %#v", defaults.Database)
+	}
+	for path, label := range map[string]string{
+		"Name":          "defaults",
+		"Debug":         "flags",
+		"Labels[env]":   "env",
+		"Labels[team]":  "file",
+		"Hosts[0]":      "file",
+		"Database.Host": "flags",
+		"Database.Port": "env",
+	} {
+		got, ok := r.Source(path)
+		if !ok || got != label {
+			t.Errorf("got source %q of %s, want %q", got, path, label)
+		}
+	}
+}
+
+func TestInjectLayersMap(t *testing.T) {
+	a := map[string]interface{}{
+		"server": map[string]interface{}{
+			"host": "localhost",
+			"port": 80,
+		},
+	}
+	var d map[string]interface{}
+	_, err := InjectLayers(&d,
+		Layer{
+			Name:   "a",
+			Source: a,
+		},
+		Layer{
+			Name: "b",
+			Source: map[string]interface{}{
+				"server": map[string]interface{}{
+					"port": 8080,
+				},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"server": map[string]interface{}{
+			"host": "localhost",
+			"port": 8080,
+		},
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+	if port := a["server"].(map[string]interface{})["port"]; port != 80 {
+		t.Errorf("layer source is changed to %#v", a)
+	}
+}
+
+func TestInjectLayersKeyCollision(t *testing.T) {
+	var d map[string]string
+	_, err := InjectLayers(&d,
+		Layer{
+			Name:   "a",
+			Source: map[string]string{"1": "a"},
+		},
+		Layer{
+			Name:   "b",
+			Source: map[interface{}]string{1: "b"},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d["1"] != "b" {
+		t.Errorf("%T destination %#v is not overridden", d, d)
+	}
+
+	_, err = InjectLayers(&d, Layer{
+		Name:   "c",
+		Source: map[interface{}]string{1: "c", "1": "d"},
+	})
+	var kerr *KeyCollisionError
+	if !errors.As(err, &kerr) {
+		t.Fatalf("Expected KeyCollisionError, but got %#v", err)
+	}
+}
+
+type layeredServer struct {
+	Host  string   `taint:"host,required"`
+	Port  int      `taint:"port"`
+	Hosts []string `taint:"hosts"`
+}
+
+func (s layeredServer) Validate() error {
+	if s.Port == 0 {
+		return errors.New("port is not set")
+	}
+	return nil
+}
+
+func TestInjectLayersChecks(t *testing.T) {
+	var s layeredServer
+	r, err := InjectLayers(&s,
+		Layer{
+			Name:   "file",
+			Source: map[string]interface{}{"host": "localhost", "hosts": []interface{}{"a", "b", "c"}},
+		},
+		Layer{
+			Name:   "env",
+			Source: map[string]interface{}{"port": 81, "hosts": []interface{}{"d"}},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := layeredServer{Host: "localhost", Port: 81, Hosts: []string{"d"}}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", s, s, expected)
+	}
+	if got, want := r.Paths(), []string{"Host", "Port", "Hosts[0]"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got paths %q, want %q", got, want)
+	}
+
+	_, err = InjectLayers(new(layeredServer),
+		Layer{Name: "file", Source: map[string]interface{}{"port": 80}},
+		Layer{Name: "env", Source: map[string]interface{}{"port": 81}},
+	)
+	var rerr *FieldRequiredError
+	if !errors.As(err, &rerr) || rerr.FieldName != "host" {
+		t.Errorf("Expected FieldRequiredError for host, but got %#v", err)
+	}
+
+	_, err = InjectLayers(new(layeredServer),
+		Layer{Name: "file", Source: map[string]interface{}{"host": "localhost"}},
+		Layer{Name: "env", Source: map[string]interface{}{"hosts": []interface{}{"a"}}},
+	)
+	var verr *ValidatorError
+	if !errors.As(err, &verr) {
+		t.Errorf("Expected ValidatorError, but got %#v", err)
+	}
+}
+
+func TestInjectLayersClear(t *testing.T) {
+	var c layeredConfig
+	r, err := InjectLayers(&c,
+		Layer{
+			Name: "file",
+			Source: map[string]interface{}{
+				"hosts":    []interface{}{"a", "b"},
+				"database": map[string]interface{}{"host": "localhost"},
+			},
+		},
+		Layer{
+			Name:   "env",
+			Source: map[string]interface{}{"hosts": []interface{}{}, "database": nil},
+		},
+		Layer{
+			Name:   "flags",
+			Source: map[string]interface{}{"database": map[string]interface{}{"port": 5432}},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := layeredConfig{
+		Hosts:    []string{},
+		Database: &layeredDatabase{Port: 5432},
+	}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", c, c, expected)
+	}
+	if got, want := r.Paths(), []string{"Hosts", "Database.Port"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got paths %q, want %q", got, want)
+	}
+	if got, _ := r.Source("Hosts"); got != "env" {
+		t.Errorf("got source %q of Hosts, want %q", got, "env")
+	}
+}
+
+func TestEnvSourceNumberedKeys(t *testing.T) {
+	expected := make([]string, 12)
+	for i := range expected {
+		expected[i] = strconv.Itoa(i)
+		t.Setenv("ENV_SOURCE_TEST_LIST_"+expected[i], expected[i])
+	}
+	var d struct {
+		List []string
+	}
+	if err := Inject(EnvSource("ENV_SOURCE_TEST_"), &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d.List, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d.List, d.List, expected)
+	}
+}
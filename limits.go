@@ -0,0 +1,74 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+// Limit names that are reported by LimitError.
+const (
+	LimitDepth      = "depth"
+	LimitLength     = "length"
+	LimitMapEntries = "map entries"
+	LimitElements   = "elements"
+)
+
+// enter increments the depth of nested values, checking the depth limit. The
+// root value is at depth 1.
+func (in *injection) enter(path string) error {
+	if in.maxDepth > 0 && in.depth >= in.maxDepth {
+		return &LimitError{
+			Path:  path,
+			Limit: LimitDepth,
+			Max:   in.maxDepth,
+			Value: in.depth + 1,
+		}
+	}
+	in.depth++
+	return nil
+}
+
+func (in *injection) leave() {
+	in.depth--
+}
+
+// checkLength checks limits for a slice or an array of length n, before it
+// is allocated.
+func (in *injection) checkLength(path string, n int) error {
+	if in.maxLength > 0 && n > in.maxLength {
+		return &LimitError{
+			Path:  path,
+			Limit: LimitLength,
+			Max:   in.maxLength,
+			Value: n,
+		}
+	}
+	return in.addElements(path, n)
+}
+
+// checkMapEntries checks limits for a map with n entries, before it is
+// allocated.
+func (in *injection) checkMapEntries(path string, n int) error {
+	if in.maxMapEntries > 0 && n > in.maxMapEntries {
+		return &LimitError{
+			Path:  path,
+			Limit: LimitMapEntries,
+			Max:   in.maxMapEntries,
+			Value: n,
+		}
+	}
+	return in.addElements(path, n)
+}
+
+func (in *injection) addElements(path string, n int) error {
+	in.elements += n
+	if in.maxElements > 0 && in.elements > in.maxElements {
+		return &LimitError{
+			Path:  path,
+			Limit: LimitElements,
+			Max:   in.maxElements,
+			Value: in.elements,
+		}
+	}
+	return nil
+}
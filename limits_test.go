@@ -0,0 +1,158 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	type Item struct {
+		Values []int
+		Labels map[string]string
+	}
+	items := make([]interface{}, 5)
+	for i := range items {
+		items[i] = map[string]interface{}{
+			"Values": []interface{}{1, 2, 3},
+			"Labels": map[string]interface{}{"a": "b", "c": "d"},
+		}
+	}
+	nested := interface{}("leaf")
+	for i := 0; i < 10; i++ {
+		nested = []interface{}{nested}
+	}
+
+	for _, tc := range []struct {
+		name  string
+		opt   Option
+		src   interface{}
+		dst   interface{}
+		path  string
+		limit string
+		value int
+	}{
+		{
+			name:  "length",
+			opt:   WithMaxLength(4),
+			src:   items,
+			dst:   new([]Item),
+			path:  "",
+			limit: LimitLength,
+			value: 5,
+		},
+		{
+			name:  "nested length",
+			opt:   WithMaxLength(2),
+			src:   items[:2],
+			dst:   new([]Item),
+			path:  "[0].Values",
+			limit: LimitLength,
+			value: 3,
+		},
+		{
+			name:  "array length",
+			opt:   WithMaxLength(2),
+			src:   []int{1, 2, 3},
+			dst:   new([4]int),
+			path:  "",
+			limit: LimitLength,
+			value: 3,
+		},
+		{
+			name:  "map entries",
+			opt:   WithMaxMapEntries(1),
+			src:   items,
+			dst:   new([]Item),
+			path:  "[0].Labels",
+			limit: LimitMapEntries,
+			value: 2,
+		},
+		{
+			name:  "struct to map entries",
+			opt:   WithMaxMapEntries(1),
+			src:   Item{},
+			dst:   new(map[string]interface{}),
+			path:  "",
+			limit: LimitMapEntries,
+			value: 2,
+		},
+		{
+			name:  "elements",
+			opt:   WithMaxElements(20),
+			src:   items,
+			dst:   new([]Item),
+			path:  "[3].Values",
+			limit: LimitElements,
+			value: 23,
+		},
+		{
+			name:  "depth",
+			opt:   WithMaxDepth(5),
+			src:   nested,
+			dst:   new(interface{}),
+			path:  "",
+			limit: LimitDepth,
+			value: 0,
+		},
+		{
+			name:  "nested depth",
+			opt:   WithMaxDepth(5),
+			src:   nested,
+			dst:   new([][][][][][][][][][]string),
+			path:  "[0][0][0][0][0]",
+			limit: LimitDepth,
+			value: 6,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewInjector(tc.opt).Inject(tc.src, tc.dst)
+			if tc.value == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var lerr *LimitError
+			if !errors.As(err, &lerr) {
+				t.Fatalf("Expected LimitError, but got %#v", err)
+			}
+			if lerr.Path != tc.path {
+				t.Errorf("got path %q, want %q", lerr.Path, tc.path)
+			}
+			if lerr.Limit != tc.limit {
+				t.Errorf("got limit %q, want %q", lerr.Limit, tc.limit)
+			}
+			if lerr.Value != tc.value {
+				t.Errorf("got value %v, want %v", lerr.Value, tc.value)
+			}
+		})
+	}
+}
+
+func TestLimitsNotExceeded(t *testing.T) {
+	type Item struct {
+		Values []int
+	}
+	s := []interface{}{
+		map[string]interface{}{"Values": []interface{}{1, 2}},
+		map[string]interface{}{"Values": []interface{}{3}},
+	}
+	in := NewInjector(
+		WithMaxDepth(4),
+		WithMaxLength(2),
+		WithMaxMapEntries(1),
+		WithMaxElements(5),
+	)
+	var d []Item
+	if err := in.Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if len(d) != 2 || len(d[0].Values) != 2 {
+		t.Errorf("unexpected destination %#v", d)
+	}
+}
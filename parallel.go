@@ -0,0 +1,129 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"maps"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultParallelThreshold is the minimal number of slice or array elements
+// that are injected in parallel when parallelism is enabled.
+const DefaultParallelThreshold = 1024
+
+// WithParallelism configures Injector to inject elements of slices and arrays
+// with at least the threshold number of elements in n goroutines. Values
+// less than 2 disable parallelism, which is the default. If injection of
+// more than one element fails, the error for the element with the lowest
+// index is returned. Field hooks and converters must be safe for concurrent
+// use when parallelism is enabled.
+//
+// Elements are always injected sequentially when shared references are
+// preserved, the number of elements is limited, or when assignments or set
+// paths are recorded, as they require the state of the whole injection.
+func WithParallelism(n int) Option {
+	return func(in *Injector) {
+		in.parallelism = n
+	}
+}
+
+// WithParallelThreshold sets the minimal number of slice or array elements
+// that are injected in parallel. The default is DefaultParallelThreshold.
+func WithParallelThreshold(n int) Option {
+	return func(in *Injector) {
+		in.parallelThreshold = n
+	}
+}
+
+// parallel returns true if n elements should be injected in parallel.
+func (in *injection) parallel(n int) bool {
+	return in.parallelism > 1 &&
+		n >= in.parallelThreshold &&
+		n > 1 &&
+		in.shared == nil &&
+		in.maxElements == 0 &&
+		!in.recording()
+}
+
+// injectElements injects first n elements of the source slice or array into
+// elements of the destination with the same indexes.
+func (in *injection) injectElements(srcValue, dstValue reflect.Value, n int, path string) error {
+	if t := dstValue.Type().Elem(); srcValue.Type().Elem() == t && in.direct(t) {
+		reflect.Copy(dstValue, srcValue)
+		return in.checkContextN(path, n)
+	}
+	if !in.parallel(n) {
+		for i := 0; i < n; i++ {
+			if err := in.injectElement(srcValue, dstValue, i, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	workers := min(in.parallelism, n)
+	size := (n + workers - 1) / workers
+	errs := make([]error, workers)
+	// failed is the lowest index of an element that failed so far, so that
+	// workers stop injecting elements that can not be reported.
+	var failed atomic.Int64
+	failed.Store(int64(n))
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start, end := w*size, min((w+1)*size, n)
+		if start >= end {
+			break
+		}
+		wg.Add(1)
+		go func(w int, child *injection) {
+			defer wg.Done()
+			for i := start; i < end && int64(i) < failed.Load(); i++ {
+				if err := child.injectElement(srcValue, dstValue, i, path); err != nil {
+					errs[w] = err
+					for {
+						f := failed.Load()
+						if int64(i) >= f || failed.CompareAndSwap(f, int64(i)) {
+							break
+						}
+					}
+					return
+				}
+			}
+		}(w, in.fork())
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// injectElement injects the source element at the index into the
+// destination element with the same index.
+func (in *injection) injectElement(srcValue, dstValue reflect.Value, i int, path string) error {
+	if in.setDirect(srcValue.Index(i), dstValue.Index(i)) {
+		return in.checkContext(path)
+	}
+	return in.inject(srcValue.Index(i), dstValue.Index(i).Addr(), indexPath(path, i))
+}
+
+// fork returns the injection state for a goroutine that injects elements in
+// parallel with other goroutines.
+func (in *injection) fork() *injection {
+	return &injection{
+		Injector:   in.Injector,
+		ctx:        in.ctx,
+		done:       in.done,
+		visiting:   maps.Clone(in.visiting),
+		depth:      in.depth,
+		merge:      in.merge,
+		converting: in.converting,
+	}
+}
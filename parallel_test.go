@@ -0,0 +1,159 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+type parallelRecord struct {
+	ID    int      `taint:"id"`
+	Name  string   `taint:"name,nonzero"`
+	Tags  []string `taint:"tags"`
+	Inner *struct {
+		Value float64 `taint:"value"`
+	} `taint:"inner"`
+}
+
+func parallelRows(n int) []map[string]interface{} {
+	rows := make([]map[string]interface{}, n)
+	for i := range rows {
+		rows[i] = map[string]interface{}{
+			"id":    i,
+			"name":  "row" + strconv.Itoa(i),
+			"tags":  []interface{}{"a", strconv.Itoa(i)},
+			"inner": map[string]interface{}{"value": float64(i) / 2},
+		}
+	}
+	return rows
+}
+
+func TestWithParallelism(t *testing.T) {
+	rows := parallelRows(1000)
+
+	var expected []parallelRecord
+	if err := Inject(rows, &expected); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []int{2, 3, 8, 2000} {
+		in := NewInjector(WithParallelism(n), WithParallelThreshold(10))
+		var got []parallelRecord
+		if err := in.Inject(rows, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("parallelism %v: destination is not equal to the sequential injection", n)
+		}
+	}
+}
+
+func TestWithParallelismArray(t *testing.T) {
+	var src [64][]int
+	for i := range src {
+		src[i] = []int{i, i * 2}
+	}
+	in := NewInjector(WithParallelism(4), WithParallelThreshold(2), WithValueConversion())
+	var dst [64][]int64
+	if err := in.Inject(src, &dst); err != nil {
+		t.Fatal(err)
+	}
+	for i := range dst {
+		if expected := []int64{int64(i), int64(i * 2)}; !reflect.DeepEqual(dst[i], expected) {
+			t.Errorf("%T destination %#v is not set to %#v", dst[i], dst[i], expected)
+		}
+	}
+}
+
+func TestWithParallelismError(t *testing.T) {
+	rows := parallelRows(500)
+	for _, i := range []int{499, 320, 17} {
+		rows[i]["name"] = ""
+	}
+	in := NewInjector(WithParallelism(8), WithParallelThreshold(10))
+	for range 20 {
+		var dst []parallelRecord
+		err := in.Inject(rows, &dst)
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("got error %v, want %T", err, verr)
+		}
+		if expected := "[17].Name"; verr.Path != expected {
+			t.Fatalf("got error path %q, want %q", verr.Path, expected)
+		}
+	}
+}
+
+func TestWithParallelismCycle(t *testing.T) {
+	root := &nodeDTO{Name: "root"}
+	for i := range 100 {
+		root.Children = append(root.Children, &nodeDTO{Name: strconv.Itoa(i)})
+	}
+	root.Children[42].Parent = root
+
+	in := NewInjector(WithParallelism(4), WithParallelThreshold(10))
+	var dst Node
+	err := in.Inject(root, &dst)
+	var cerr *CycleError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("got error %v, want %T", err, cerr)
+	}
+	if expected := "Children[42].Parent"; cerr.Path != expected {
+		t.Errorf("got error path %q, want %q", cerr.Path, expected)
+	}
+}
+
+func TestWithParallelismFieldHook(t *testing.T) {
+	var calls atomic.Int64
+	in := NewInjector(
+		WithParallelism(4),
+		WithParallelThreshold(10),
+		WithFieldHook(func(_ context.Context, _ string, src, _ interface{}) (interface{}, error) {
+			calls.Add(1)
+			return src, nil
+		}),
+	)
+	var dst []parallelRecord
+	if err := in.Inject(parallelRows(100), &dst); err != nil {
+		t.Fatal(err)
+	}
+	// Four fields of records and one field of inner structs.
+	if got, expected := calls.Load(), int64(500); got != expected {
+		t.Errorf("got %v field hook calls, want %v", got, expected)
+	}
+}
+
+func TestWithParallelismSequential(t *testing.T) {
+	rows := parallelRows(100)
+	for _, opt := range []Option{
+		WithSharedReferences(),
+		WithMaxElements(1000),
+	} {
+		in := NewInjector(WithParallelism(4), WithParallelThreshold(10), opt)
+		var dst []parallelRecord
+		if err := in.Inject(rows, &dst); err != nil {
+			t.Fatal(err)
+		}
+		if len(dst) != len(rows) {
+			t.Errorf("got %v elements, want %v", len(dst), len(rows))
+		}
+	}
+
+	in := NewInjector(WithParallelism(4), WithParallelThreshold(10))
+	result := new(Result)
+	var dst []parallelRecord
+	if err := in.InjectWithResult(rows, &dst, result, "rows"); err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsSet("[99].Inner.Value") {
+		t.Errorf("path [99].Inner.Value is not set in result %v", result.Paths())
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"reflect"
+	"strings"
+)
+
+// prefixSource is a Source view of a flat source struct, map or Source with
+// keys that start with the prefix. It is used to inject struct and map fields
+// that have the prefix tag option, so that the AddressCity key of the source
+// is injected into the City field of the Address field.
+type prefixSource struct {
+	v      reflect.Value
+	prefix string
+}
+
+func newPrefixSource(v reflect.Value, prefix string) prefixSource {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if _, ok := asSource(v); ok || v.IsNil() {
+			break
+		}
+		v = v.Elem()
+	}
+	return prefixSource{v: v, prefix: prefix}
+}
+
+func (s prefixSource) Keys() (keys []string) {
+	if src, ok := asSource(s.v); ok {
+		for _, k := range src.Keys() {
+			if strings.HasPrefix(k, s.prefix) && len(k) > len(s.prefix) {
+				keys = append(keys, k[len(s.prefix):])
+			}
+		}
+		return keys
+	}
+	switch s.v.Kind() {
+	case reflect.Map:
+		for _, k := range s.v.MapKeys() {
+			k = reflect.Indirect(k)
+			if k.Kind() == reflect.Interface {
+				k = k.Elem()
+			}
+			if k.Kind() != reflect.String {
+				continue
+			}
+			if key := k.String(); strings.HasPrefix(key, s.prefix) && len(key) > len(s.prefix) {
+				keys = append(keys, key[len(s.prefix):])
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < s.v.NumField(); i++ {
+			f := s.v.Type().Field(i)
+			if isExported(f) && strings.HasPrefix(f.Name, s.prefix) && len(f.Name) > len(s.prefix) {
+				keys = append(keys, f.Name[len(s.prefix):])
+			}
+		}
+	}
+	return keys
+}
+
+func (s prefixSource) Lookup(key string) (interface{}, bool) {
+	if src, ok := asSource(s.v); ok {
+		return src.Lookup(s.prefix + key)
+	}
+	switch s.v.Kind() {
+	case reflect.Map:
+		k, ok := stringKey(s.prefix+key, s.v.Type().Key())
+		if !ok {
+			return nil, false
+		}
+		if v := s.v.MapIndex(k); v.IsValid() {
+			return v.Interface(), true
+		}
+	case reflect.Struct:
+		v, ok := exportedFieldByName(s.v, s.prefix+key)
+		if !ok && key != "" {
+			v, ok = exportedFieldByName(s.v, s.prefix+strings.ToUpper(key[:1])+key[1:])
+		}
+		if ok {
+			return v.Interface(), true
+		}
+	}
+	return nil, false
+}
+
+func (s prefixSource) Len() int {
+	return 0
+}
+
+func (s prefixSource) Index(int) interface{} {
+	return nil
+}
+
+// lookupPrefixed returns the field of the nested source struct that is
+// in the field with the prefix tag option, if the key starts with the prefix,
+// so that the AddressCity field of a flat destination struct is injected from
+// the City field of the Address field.
+func lookupPrefixed(srcValue reflect.Value, key, tagKey string) (reflect.Value, bool) {
+	for i := 0; i < srcValue.NumField(); i++ {
+		f := srcValue.Type().Field(i)
+		if !isExported(f) {
+			continue
+		}
+		prefix, ok := tagOption(f.Tag, tagKey, "prefix")
+		if !ok || !strings.HasPrefix(key, prefix) || len(key) == len(prefix) {
+			continue
+		}
+		v := reflect.Indirect(srcValue.Field(i))
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		name := key[len(prefix):]
+		if v, ok := exportedFieldByName(v, name); ok {
+			return v, true
+		}
+		if v, ok := lookupPrefixed(v, name, tagKey); ok {
+			return v, true
+		}
+	}
+	return reflect.Value{}, false
+}
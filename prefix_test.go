@@ -0,0 +1,230 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"reflect"
+	"testing"
+)
+
+type userRow struct {
+	UserID          int
+	UserName        string
+	UserAddressCity string
+	UserAddressZip  string
+	Active          bool
+}
+
+type UserAddress struct {
+	City string
+	Zip  string
+}
+
+type PrefixedUser struct {
+	ID      int
+	Name    string
+	Address *UserAddress `taint:",prefix=Address"`
+}
+
+type Account struct {
+	User   PrefixedUser `taint:",prefix=User"`
+	Active bool
+}
+
+func TestInjectPrefixFromStruct(t *testing.T) {
+	s := userRow{
+		UserID:          1,
+		UserName:        "alice",
+		UserAddressCity: "Springfield",
+		UserAddressZip:  "12345",
+		Active:          true,
+	}
+	expected := Account{
+		User: PrefixedUser{
+			ID:   1,
+			Name: "alice",
+			Address: &UserAddress{
+				City: "Springfield",
+				Zip:  "12345",
+			},
+		},
+		Active: true,
+	}
+	var d Account
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+}
+
+func TestInjectPrefixFromMap(t *testing.T) {
+	s := map[string]interface{}{
+		"UserID":   1,
+		"UserName": "alice",
+		"Active":   true,
+	}
+	expected := Account{
+		User: PrefixedUser{
+			ID:   1,
+			Name: "alice",
+		},
+		Active: true,
+	}
+	var d Account
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+}
+
+func TestInjectPrefixMapField(t *testing.T) {
+	s := map[string]string{
+		"label_env":  "prod",
+		"label_team": "core",
+		"name":       "api",
+	}
+	expected := struct {
+		Name   string            `taint:"name"`
+		Labels map[string]string `taint:",prefix=label_"`
+	}{
+		Name: "api",
+		Labels: map[string]string{
+			"env":  "prod",
+			"team": "core",
+		},
+	}
+	d := expected
+	d.Name, d.Labels = "", nil
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+}
+
+func TestInjectPrefixToMap(t *testing.T) {
+	s := Account{
+		User: PrefixedUser{
+			ID:   1,
+			Name: "alice",
+			Address: &UserAddress{
+				City: "Springfield",
+				Zip:  "12345",
+			},
+		},
+		Active: true,
+	}
+	expected := map[string]interface{}{
+		"UserID":          1,
+		"UserName":        "alice",
+		"UserAddressCity": "Springfield",
+		"UserAddressZip":  "12345",
+		"Active":          true,
+	}
+	var d map[string]interface{}
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+
+	s.User.Address = nil
+	delete(expected, "UserAddressCity")
+	delete(expected, "UserAddressZip")
+	d = nil
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+}
+
+func TestInjectPrefixToStruct(t *testing.T) {
+	s := Account{
+		User: PrefixedUser{
+			ID:   1,
+			Name: "alice",
+			Address: &UserAddress{
+				City: "Springfield",
+			},
+		},
+		Active: true,
+	}
+	expected := userRow{
+		UserID:          1,
+		UserName:        "alice",
+		UserAddressCity: "Springfield",
+		Active:          true,
+	}
+	var d userRow
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+}
+
+func TestInjectPrefixNestedSource(t *testing.T) {
+	type account struct {
+		User   PrefixedUser
+		Active bool
+	}
+	expected := Account{
+		User: PrefixedUser{
+			ID:   1,
+			Name: "alice",
+			Address: &UserAddress{
+				City: "Paris",
+			},
+		},
+		Active: true,
+	}
+	for _, tc := range []struct {
+		name string
+		src  interface{}
+	}{
+		{
+			name: "same type",
+			src:  expected,
+		},
+		{
+			name: "nested struct",
+			src: account{
+				User:   expected.User,
+				Active: true,
+			},
+		},
+		{
+			name: "nested map",
+			src: map[string]interface{}{
+				"User": map[string]interface{}{
+					"ID":      1,
+					"Name":    "alice",
+					"Address": map[string]interface{}{"City": "Paris"},
+				},
+				"Active": true,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var d Account
+			if err := Inject(tc.src, &d); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(d, expected) {
+				t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"context"
+	"reflect"
+	"slices"
+)
+
+// Result holds paths of destination values that are set by injections, with
+// labels of sources that they are set from. The same Result can be passed to
+// several injections into the same destination, and the label of the last
+// source that sets a value is kept. The zero value is ready to use.
+type Result struct {
+	paths   []string
+	sources map[string]string
+}
+
+// Paths returns paths of all set destination values in the order in which
+// they are first set.
+func (r *Result) Paths() []string {
+	return append([]string(nil), r.paths...)
+}
+
+// IsSet returns true if the destination value at the path is set.
+func (r *Result) IsSet(path string) bool {
+	_, ok := r.sources[path]
+	return ok
+}
+
+// Source returns the label of the source that the destination value at the
+// path is set from.
+func (r *Result) Source(path string) (label string, ok bool) {
+	label, ok = r.sources[path]
+	return label, ok
+}
+
+func (r *Result) set(path, label string) {
+	if r.sources == nil {
+		r.sources = make(map[string]string)
+	}
+	if _, ok := r.sources[path]; !ok {
+		r.paths = append(r.paths, path)
+	}
+	r.sources[path] = label
+}
+
+// unset removes the path.
+func (r *Result) unset(path string) {
+	if _, ok := r.sources[path]; !ok {
+		return
+	}
+	delete(r.sources, path)
+	r.paths = slices.DeleteFunc(r.paths, func(p string) bool {
+		return p == path
+	})
+}
+
+// drop removes paths of values nested in the value at the path.
+func (r *Result) drop(path string) {
+	r.paths = slices.DeleteFunc(r.paths, func(p string) bool {
+		if isUnder(p, path) {
+			delete(r.sources, p)
+			return true
+		}
+		return false
+	})
+}
+
+// InjectWithResult injects the source into the destination in the same way
+// as Inject, recording paths of set destination values in the result with
+// the label of the source. Struct fields, slice and array elements and map
+// entries are recorded by their paths if they are set from values that are
+// not structs, maps, slices or arrays.
+func (in *Injector) InjectWithResult(src, dst interface{}, result *Result, label string) error {
+	dstValue := reflect.ValueOf(dst)
+	if !dstValue.IsValid() {
+		return &InvalidInjectError{}
+	}
+	if dstValue.Kind() != reflect.Ptr || dstValue.IsNil() {
+		return &InvalidInjectError{dstValue.Type()}
+	}
+	i := in.newInjection(context.Background())
+	defer i.release()
+	i.result = result
+	i.label = label
+	return i.inject(reflect.ValueOf(src), dstValue, "")
+}
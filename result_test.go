@@ -0,0 +1,115 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInjectWithResult(t *testing.T) {
+	type Database struct {
+		Host string `taint:"host"`
+		Port int    `taint:"port"`
+	}
+	type Config struct {
+		Name     string   `taint:"name"`
+		Debug    bool     `taint:"debug"`
+		Tags     []string `taint:"tags"`
+		Database Database `taint:"database"`
+	}
+
+	var c Config
+	var r Result
+	in := NewInjector()
+	if err := in.InjectWithResult(map[string]interface{}{
+		"name": "app",
+		"tags": []string{"a"},
+		"database": map[string]interface{}{
+			"host": "localhost",
+			"port": 5432,
+		},
+	}, &c, &r, "file"); err != nil {
+		t.Fatal(err)
+	}
+	if err := in.InjectWithResult(map[string]interface{}{
+		"database": map[string]interface{}{
+			"port": 6432,
+		},
+	}, &c, &r, "env"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Config{
+		Name: "app",
+		Tags: []string{"a"},
+		Database: Database{
+			Host: "localhost",
+			Port: 6432,
+		},
+	}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", c, c, expected)
+	}
+	expectedPaths := []string{"Name", "Tags[0]", "Database.Host", "Database.Port"}
+	if got := r.Paths(); !reflect.DeepEqual(got, expectedPaths) {
+		t.Errorf("got paths %v, want %v", got, expectedPaths)
+	}
+	for path, label := range map[string]string{
+		"Name":          "file",
+		"Tags[0]":       "file",
+		"Database.Host": "file",
+		"Database.Port": "env",
+	} {
+		got, ok := r.Source(path)
+		if !ok || got != label {
+			t.Errorf("got source %q of %s, want %q", got, path, label)
+		}
+	}
+	if r.IsSet("Debug") {
+		t.Error("Debug is set")
+	}
+}
+
+func TestResultZero(t *testing.T) {
+	var r Result
+	if p := r.Paths(); len(p) != 0 {
+		t.Errorf("got paths %v, want none", p)
+	}
+	if _, ok := r.Source("Name"); ok {
+		t.Error("Name has source")
+	}
+}
+
+func TestInjectWithResultClear(t *testing.T) {
+	type Config struct {
+		Name *string
+		Tags []string
+		M    map[string]int
+	}
+	var c Config
+	var r Result
+	in := NewInjector()
+	if err := in.InjectWithResult(map[string]interface{}{
+		"Name": "app",
+		"Tags": []string{"a"},
+		"M":    map[string]int{"a": 1},
+	}, &c, &r, "file"); err != nil {
+		t.Fatal(err)
+	}
+	if err := in.InjectWithResult(map[string]interface{}{
+		"Name": nil,
+		"Tags": nil,
+		"M":    map[string]int{},
+	}, &c, &r, "env"); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"Name", "Tags", "M"} {
+		if got, ok := r.Source(path); !ok || got != "env" {
+			t.Errorf("got source %q of %s, want %q", got, path, "env")
+		}
+	}
+}
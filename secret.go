@@ -0,0 +1,275 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"errors"
+	"reflect"
+)
+
+// DefaultSecretMask is the default string that replaces values of struct
+// fields with the secret tag option.
+const DefaultSecretMask = "******"
+
+// errSecret replaces errors that may contain values of secret fields.
+var errSecret = errors.New("secret value")
+
+// WithSecretMask sets the string that replaces values of struct fields with
+// the secret tag option when structs are injected into maps, compared by
+// Diff or redacted by Redact. The default is DefaultSecretMask.
+func WithSecretMask(mask string) Option {
+	return func(in *Injector) {
+		in.secretMask = mask
+	}
+}
+
+// Redacted returns a deep copy of the value in the same way as Clone, with
+// values of all struct fields with the secret tag option replaced by
+// DefaultSecretMask, so that it can be safely logged.
+func Redacted[T any](v T) (T, error) {
+	var c T
+	err := NewInjector().Redact(v, &c)
+	return c, err
+}
+
+// Redact injects the deep copy of the source into the destination in the
+// same way as DeepCopy, replacing values of struct fields with the secret
+// tag option by the mask. Secret fields of string types and pointers to
+// strings are set to the mask, interfaces are set to the mask string if they
+// can hold it, and all other secret fields are set to their zero values.
+func (in *Injector) Redact(src, dst interface{}) error {
+	if err := in.copier().Inject(src, dst); err != nil {
+		return err
+	}
+	r := &redactor{
+		tagKey:  in.tagKey,
+		mask:    in.secretMask,
+		visited: make(map[visit]struct{}),
+	}
+	r.redact(reflect.ValueOf(dst).Elem())
+	return nil
+}
+
+// redactedCopy returns a redacted deep copy of the value.
+func (in *Injector) redactedCopy(v reflect.Value) (reflect.Value, error) {
+	c := reflect.New(v.Type())
+	if err := in.Redact(v.Interface(), c.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	return c.Elem(), nil
+}
+
+// redactor holds the state of a single Redact call.
+type redactor struct {
+	tagKey string
+	mask   string
+	// visited holds pointers, maps and slices that are already redacted,
+	// as they may be shared or be a part of a cycle.
+	visited map[visit]struct{}
+}
+
+func (r *redactor) redact(v reflect.Value) {
+	if key, ok := newVisit(v, nil); ok {
+		if _, ok := r.visited[key]; ok {
+			return
+		}
+		r.visited[key] = struct{}{}
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			r.redact(v.Elem())
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		// Values in interfaces are not settable, so the copy is redacted.
+		e := reflect.New(v.Elem().Type()).Elem()
+		e.Set(v.Elem())
+		r.redact(e)
+		v.Set(e)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !isExported(f) {
+				continue
+			}
+			if isSecret(f, r.tagKey) {
+				maskValue(v.Field(i), r.mask)
+				continue
+			}
+			r.redact(v.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			r.redact(v.Index(i))
+		}
+	case reflect.Map:
+		e := reflect.New(v.Type().Elem()).Elem()
+		iter := v.MapRange()
+		for iter.Next() {
+			e.SetIterValue(iter)
+			r.redact(e)
+			v.SetMapIndex(iter.Key(), e)
+		}
+	}
+}
+
+func isSecret(f reflect.StructField, tagKey string) bool {
+	return tagContains(f.Tag, tagKey, "secret")
+}
+
+// maskValue sets the value to the mask if it is a string, a pointer to a
+// string or an interface that can hold a string, or to the zero value
+// otherwise.
+func maskValue(v reflect.Value, mask string) {
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(mask)
+	case v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.String && !v.IsNil():
+		// The pointer may be shared with other values, so a new one is set.
+		p := reflect.New(v.Type().Elem())
+		p.Elem().SetString(mask)
+		v.Set(p)
+	case v.Kind() == reflect.Interface && stringType.AssignableTo(v.Type()):
+		v.Set(reflect.ValueOf(mask))
+	default:
+		v.SetZero()
+	}
+}
+
+// mayHaveSecrets returns true if values of the type may contain struct
+// fields with the secret tag option, directly or through interfaces.
+func mayHaveSecrets(t reflect.Type, tagKey string, seen map[reflect.Type]struct{}) bool {
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+	default:
+		return false
+	}
+	if seen == nil {
+		seen = make(map[reflect.Type]struct{})
+	}
+	if _, ok := seen[t]; ok {
+		return false
+	}
+	seen[t] = struct{}{}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return mayHaveSecrets(t.Elem(), tagKey, seen)
+	case reflect.Map:
+		return mayHaveSecrets(t.Key(), tagKey, seen) || mayHaveSecrets(t.Elem(), tagKey, seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if isExported(f) && (isSecret(f, tagKey) || mayHaveSecrets(f.Type, tagKey, seen)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasSecrets returns true if the value contains struct fields with the
+// secret tag option. Unlike mayHaveSecrets, values held by interfaces are
+// inspected.
+func hasSecrets(v reflect.Value, tagKey string, visited map[visit]struct{}) bool {
+	if !mayHaveSecrets(v.Type(), tagKey, nil) {
+		return false
+	}
+	if key, ok := newVisit(v, nil); ok {
+		if _, ok := visited[key]; ok {
+			return false
+		}
+		visited[key] = struct{}{}
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return !v.IsNil() && hasSecrets(v.Elem(), tagKey, visited)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if isExported(f) && (isSecret(f, tagKey) || hasSecrets(v.Field(i), tagKey, visited)) {
+				return true
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if hasSecrets(v.Index(i), tagKey, visited) {
+				return true
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if hasSecrets(iter.Key(), tagKey, visited) || hasSecrets(iter.Value(), tagKey, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// holdsInterfaces returns true if values of the type may hold interfaces, to
+// which values are assigned as they are.
+func holdsInterfaces(t reflect.Type, seen map[reflect.Type]struct{}) bool {
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+	default:
+		return false
+	}
+	if seen == nil {
+		seen = make(map[reflect.Type]struct{})
+	}
+	if _, ok := seen[t]; ok {
+		return false
+	}
+	seen[t] = struct{}{}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return holdsInterfaces(t.Elem(), seen)
+	case reflect.Map:
+		return holdsInterfaces(t.Key(), seen) || holdsInterfaces(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); isExported(f) && holdsInterfaces(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fieldError removes values from the error of injection of the struct field
+// if it has the secret tag option.
+func (in *injection) fieldError(f reflect.StructField, err error) error {
+	if !isSecret(f, in.tagKey) {
+		return err
+	}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		verr.Value = in.secretMask
+	}
+	var cerr *ConvertError
+	if errors.As(err, &cerr) {
+		cerr.Err = errSecret
+	}
+	var eerr *EnumError
+	if errors.As(err, &eerr) {
+		eerr.Value = in.secretMask
+	}
+	return err
+}
+
+// containsSecrets returns true if the value contains struct fields with the
+// secret tag option.
+func (in *Injector) containsSecrets(v reflect.Value) bool {
+	return v.IsValid() && hasSecrets(v, in.tagKey, make(map[visit]struct{}))
+}
@@ -0,0 +1,244 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type secretCredentials struct {
+	User     string  `taint:"user"`
+	Password string  `taint:"password,secret"`
+	Token    *string `taint:"token,secret"`
+	PIN      int     `taint:"pin,secret"`
+}
+
+type secretAccount struct {
+	Name        string              `taint:"name"`
+	Credentials secretCredentials   `taint:"credentials"`
+	Backup      *secretCredentials  `taint:"backup"`
+	Keys        []secretCredentials `taint:"keys"`
+	APIKey      string              `taint:"api_key,secret,nonzero"`
+}
+
+func newSecretAccount() *secretAccount {
+	token := "t0k3n"
+	return &secretAccount{
+		Name: "alice",
+		Credentials: secretCredentials{
+			User:     "alice",
+			Password: "hunter2",
+			Token:    &token,
+			PIN:      1234,
+		},
+		Backup: &secretCredentials{User: "bob", Password: "letmein"},
+		Keys:   []secretCredentials{{User: "k", Password: "s3cr3t"}},
+		APIKey: "key-123",
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	src := newSecretAccount()
+	r, err := Redacted(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mask := DefaultSecretMask
+	expected := &secretAccount{
+		Name: "alice",
+		Credentials: secretCredentials{
+			User:     "alice",
+			Password: mask,
+			Token:    &mask,
+		},
+		Backup: &secretCredentials{User: "bob", Password: mask},
+		Keys:   []secretCredentials{{User: "k", Password: mask}},
+		APIKey: mask,
+	}
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", r, r, expected)
+	}
+	if src.Credentials.Password != "hunter2" || *src.Credentials.Token != "t0k3n" || src.Backup.Password != "letmein" {
+		t.Errorf("source is changed to %#v", src)
+	}
+	if s := fmt.Sprintf("%+v %+v", *r, *r.Backup); strings.Contains(s, "hunter2") || strings.Contains(s, "letmein") {
+		t.Errorf("redacted value %s contains secrets", s)
+	}
+}
+
+func TestRedactedCycle(t *testing.T) {
+	type node struct {
+		Secret string `taint:",secret"`
+		Next   *node
+	}
+	n := &node{Secret: "a"}
+	n.Next = &node{Secret: "b", Next: n}
+	r, err := Redacted(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Secret != DefaultSecretMask || r.Next.Secret != DefaultSecretMask || r.Next.Next != r {
+		t.Errorf("unexpected redacted value %#v", r)
+	}
+}
+
+func TestRedactInterface(t *testing.T) {
+	src := map[string]interface{}{
+		"account": *newSecretAccount(),
+	}
+	var dst map[string]interface{}
+	if err := NewInjector(WithSecretMask("x")).Redact(src, &dst); err != nil {
+		t.Fatal(err)
+	}
+	a := dst["account"].(secretAccount)
+	if a.APIKey != "x" || a.Credentials.Password != "x" {
+		t.Errorf("unexpected redacted value %#v", a)
+	}
+}
+
+func TestSecretExtraction(t *testing.T) {
+	in := NewInjector(WithSecretMask("[hidden]"))
+	var m map[string]interface{}
+	if err := in.Inject(newSecretAccount(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["api_key"] != "[hidden]" {
+		t.Errorf("got api_key %#v, want %#v", m["api_key"], "[hidden]")
+	}
+	if s := fmt.Sprintf("%+v", m); strings.Contains(s, "hunter2") || strings.Contains(s, "letmein") ||
+		strings.Contains(s, "s3cr3t") || strings.Contains(s, "key-123") {
+		t.Errorf("extracted map %s contains secrets", s)
+	}
+	c := m["credentials"].(secretCredentials)
+	if c.User != "alice" || c.Password != "[hidden]" || *c.Token != "[hidden]" || c.PIN != 0 {
+		t.Errorf("unexpected credentials %#v", c)
+	}
+
+	var s map[string]string
+	if err := in.Inject(newSecretAccount().Credentials, &s); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"user": "alice", "password": "[hidden]", "token": "[hidden]", "pin": "[hidden]"}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", s, s, expected)
+	}
+}
+
+func TestSecretExtractionInterfaces(t *testing.T) {
+	type holder struct {
+		List  []interface{}            `taint:"list"`
+		Map   map[string]interface{}   `taint:"map"`
+		Lists map[string][]interface{} `taint:"lists"`
+	}
+	c := newSecretAccount().Credentials
+	src := holder{
+		List:  []interface{}{c},
+		Map:   map[string]interface{}{"c": c},
+		Lists: map[string][]interface{}{"l": {&c}},
+	}
+	var m map[string]interface{}
+	if err := Inject(src, &m); err != nil {
+		t.Fatal(err)
+	}
+	var l map[string]map[string][]interface{}
+	if err := Inject(struct {
+		Lists map[string][]interface{} `taint:"lists"`
+	}{src.Lists}, &l); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []interface{}{m, l} {
+		if s := fmt.Sprintf("%+v", v); strings.Contains(s, "hunter2") {
+			t.Errorf("extracted map %s contains secrets", s)
+		}
+	}
+	if c := m["list"].([]interface{})[0].(secretCredentials); c.User != "alice" || c.Password != DefaultSecretMask {
+		t.Errorf("unexpected credentials %#v", c)
+	}
+	if src.List[0].(secretCredentials).Password != "hunter2" {
+		t.Errorf("source is changed to %#v", src)
+	}
+}
+
+func TestRedactedInvalid(t *testing.T) {
+	type config struct {
+		Port     int    `taint:"port,min=1,required"`
+		Password string `taint:"password,secret,nonzero"`
+	}
+	r, err := Redacted(config{Password: "hunter2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := config{Password: DefaultSecretMask}
+	if r != expected {
+		t.Errorf("%T destination %#v is not set to %#v", r, r, expected)
+	}
+}
+
+func TestSecretDiff(t *testing.T) {
+	a := newSecretAccount()
+	b := newSecretAccount()
+	b.APIKey = "key-456"
+	b.Credentials.Password = "hunter3"
+	b.Backup = nil
+	b.Keys = append(b.Keys, secretCredentials{User: "l", Password: "other"})
+
+	changes, err := Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, c := range changes {
+		paths = append(paths, c.Path)
+		s := c.String()
+		for _, secret := range []string{"hunter", "letmein", "key-", "other"} {
+			if strings.Contains(s, secret) {
+				t.Errorf("change %q contains secret %q", s, secret)
+			}
+		}
+	}
+	expected := []string{"credentials.password", "backup", "keys[1]", "api_key"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("got changed paths %v, want %v", paths, expected)
+	}
+	if changes[0].Old != DefaultSecretMask || changes[0].New != DefaultSecretMask {
+		t.Errorf("unexpected change %#v", changes[0])
+	}
+}
+
+func TestSecretErrors(t *testing.T) {
+	var d secretAccount
+	err := Inject(map[string]interface{}{"api_key": ""}, &d)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("got error %v, want %T", err, verr)
+	}
+	if verr.Value != DefaultSecretMask {
+		t.Errorf("got error value %#v, want %#v", verr.Value, DefaultSecretMask)
+	}
+
+	type secretText struct {
+		Value secretUnmarshaler `taint:",secret"`
+	}
+	var s secretText
+	err = Inject(map[string]interface{}{"Value": "hunter2"}, &s)
+	var cerr *ConvertError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("got error %v, want %T", err, cerr)
+	}
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("error %q contains the secret", err)
+	}
+}
+
+type secretUnmarshaler string
+
+func (s *secretUnmarshaler) UnmarshalText(text []byte) error {
+	return fmt.Errorf("invalid value %q", text)
+}
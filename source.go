@@ -0,0 +1,127 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import "reflect"
+
+// Source is implemented by source types that can not be usefully reflected
+// as maps or structs, like lazily loaded configuration nodes or database
+// rows. Values are requested from the source only when they are needed by
+// the destination, so arbitrary tree-shaped data can be injected without
+// converting it to maps and slices first.
+//
+// Keys and Lookup are used when the destination is a struct or a map, with
+// struct field keys resolved in the same way as for map sources. Len and
+// Index are used when the destination is a slice or an array. Values returned
+// by Lookup and Index may also implement Source.
+type Source interface {
+	Keys() []string
+	Lookup(key string) (interface{}, bool)
+	Len() int
+	Index(i int) interface{}
+}
+
+var sourceType = reflect.TypeOf((*Source)(nil)).Elem()
+
+func asSource(v reflect.Value) (Source, bool) {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil, false
+	}
+	// Values of other types are not converted to interfaces, as it
+	// allocates.
+	if v.Kind() != reflect.Interface && !v.Type().Implements(sourceType) {
+		return nil, false
+	}
+	s, ok := v.Interface().(Source)
+	if ok && v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, false
+	}
+	return s, ok
+}
+
+// injectSource injects values from the Source into the destination that is
+// a struct, a map, a slice or an array. It returns false if the destination
+// is of any other kind, so that the source value itself can be injected.
+func (in *injection) injectSource(src Source, dstValue reflect.Value, path string) (handled bool, err error) {
+	dstValue = dstValue.Elem()
+	if _, ok := src.(treeSource); ok && !in.converting {
+		in.converting = true
+		defer func() { in.converting = false }()
+	}
+	switch dstValue.Kind() {
+	case reflect.Struct:
+		err := in.injectFields(reflect.ValueOf(src), dstValue, path, func(_ reflect.StructField, key string) (reflect.Value, bool) {
+			v, ok := src.Lookup(key)
+			return reflect.ValueOf(v), ok
+		})
+		if err != nil {
+			return true, err
+		}
+		return true, in.validate(dstValue, path)
+	case reflect.Map:
+		dstType := dstValue.Type()
+		keys := src.Keys()
+		if err := in.checkMapEntries(path, len(keys)); err != nil {
+			return true, err
+		}
+		dstKeys := in.makeMap(dstValue)
+		var dstKeyValue reflect.Value
+		for _, key := range keys {
+			v, ok := src.Lookup(key)
+			if !ok {
+				continue
+			}
+			srcKeyPath := keyPath(path, reflect.ValueOf(key))
+			dstKey := reflect.New(dstType.Key())
+			if err := in.injectKey(reflect.ValueOf(key), dstKey, srcKeyPath); err != nil {
+				return true, err
+			}
+			if dstKeys.collision(dstValue, dstKey.Elem()) {
+				return true, &KeyCollisionError{
+					Path: srcKeyPath,
+					Key:  dstKey.Elem().Interface(),
+				}
+			}
+			dstKeyValue = in.mapValue(dstKeyValue, dstValue, dstKey.Elem())
+			if err := in.inject(reflect.ValueOf(v), dstKeyValue, srcKeyPath); err != nil {
+				return true, err
+			}
+			dstValue.SetMapIndex(dstKey.Elem(), dstKeyValue.Elem())
+		}
+		return true, nil
+	case reflect.Slice:
+		l := src.Len()
+		if err := in.checkLength(path, l); err != nil {
+			return true, err
+		}
+		dstValue.Set(reflect.MakeSlice(dstValue.Type(), l, l))
+		for i := 0; i < l; i++ {
+			if err := in.inject(reflect.ValueOf(src.Index(i)), dstValue.Index(i).Addr(), indexPath(path, i)); err != nil {
+				return true, err
+			}
+		}
+		return true, nil
+	case reflect.Array:
+		l := src.Len()
+		if l > dstValue.Len() {
+			return true, &InvalidTypeError{
+				TypeSrc: reflect.TypeOf(src),
+				TypeDst: dstValue.Type(),
+			}
+		}
+		if err := in.checkLength(path, l); err != nil {
+			return true, err
+		}
+		dstValue.Set(reflect.Zero(dstValue.Type()))
+		for i := 0; i < l; i++ {
+			if err := in.inject(reflect.ValueOf(src.Index(i)), dstValue.Index(i).Addr(), indexPath(path, i)); err != nil {
+				return true, err
+			}
+		}
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// configNode is a lazy tree source that parses dotted keys of a flat
+// properties list only when they are looked up.
+type configNode struct {
+	props   map[string]string
+	prefix  string
+	lookups *[]string
+}
+
+func (n configNode) Keys() []string {
+	seen := make(map[string]struct{})
+	var keys []string
+	for k := range n.props {
+		if !strings.HasPrefix(k, n.prefix) {
+			continue
+		}
+		k = strings.SplitN(strings.TrimPrefix(k, n.prefix), ".", 2)[0]
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (n configNode) Lookup(key string) (interface{}, bool) {
+	*n.lookups = append(*n.lookups, n.prefix+key)
+	if v, ok := n.props[n.prefix+key]; ok {
+		return v, true
+	}
+	child := configNode{
+		props:   n.props,
+		prefix:  n.prefix + key + ".",
+		lookups: n.lookups,
+	}
+	if len(child.Keys()) == 0 {
+		return nil, false
+	}
+	return child, true
+}
+
+func (n configNode) Len() int {
+	return len(n.Keys())
+}
+
+func (n configNode) Index(i int) interface{} {
+	v, _ := n.Lookup(n.Keys()[i])
+	return v
+}
+
+func TestInjectSource(t *testing.T) {
+	type Listener struct {
+		Host string `taint:"host"`
+		Port string `taint:"port"`
+	}
+	type Config struct {
+		Name      string            `taint:"name,required"`
+		Listener  Listener          `taint:"listener"`
+		Labels    map[string]string `taint:"labels"`
+		Backends  []string          `taint:"backends"`
+		Addresses [3]string         `taint:"addresses"`
+		Ignored   string            `taint:"-"`
+	}
+	var lookups []string
+	s := configNode{
+		props: map[string]string{
+			"name":          "api",
+			"listener.host": "localhost",
+			"listener.port": "8080",
+			"labels.env":    "prod",
+			"labels.team":   "core",
+			"backends.0":    "b1",
+			"backends.1":    "b2",
+			"addresses.0":   "a1",
+			"unused.key":    "value",
+		},
+		lookups: &lookups,
+	}
+	expected := Config{
+		Name: "api",
+		Listener: Listener{
+			Host: "localhost",
+			Port: "8080",
+		},
+		Labels: map[string]string{
+			"env":  "prod",
+			"team": "core",
+		},
+		Backends:  []string{"b1", "b2"},
+		Addresses: [3]string{"a1"},
+	}
+	var d Config
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+	for _, l := range lookups {
+		if strings.HasPrefix(l, "unused") {
+			t.Errorf("unexpected lookup of %q", l)
+		}
+	}
+}
+
+func TestInjectSourceRequiredField(t *testing.T) {
+	var lookups []string
+	s := configNode{
+		props:   map[string]string{},
+		lookups: &lookups,
+	}
+	var d struct {
+		Name string `taint:"name,required"`
+	}
+	err := Inject(s, &d)
+	var ferr *FieldRequiredError
+	if !errors.As(err, &ferr) {
+		t.Fatalf("Expected FieldRequiredError, but got %#v", err)
+	}
+	if ferr.FieldName != "name" {
+		t.Errorf("Expected FieldRequiredError FieldName name, but got %#v", ferr.FieldName)
+	}
+}
+
+func TestInjectSourceToInterface(t *testing.T) {
+	var lookups []string
+	s := configNode{
+		props:   map[string]string{"a": "b"},
+		lookups: &lookups,
+	}
+	var d interface{}
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.(configNode); !ok {
+		t.Errorf("got %T, want %T", d, s)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// transforms are string transformations from struct tag options. Supported
+// options are:
+//
+//	trim      remove leading and trailing white space
+//	lower     convert to lower case
+//	upper     convert to upper case
+//	title     convert the first letter of every word to title case
+//	collapse  replace every sequence of white space with a single space
+var transforms = map[string]func(string) string{
+	"trim":     strings.TrimSpace,
+	"lower":    strings.ToLower,
+	"upper":    strings.ToUpper,
+	"title":    title,
+	"collapse": collapse,
+}
+
+// transformField applies string transformations from struct tag options to
+// the injected field value, in the order of options. They are applied to
+// strings, pointers to strings, and to elements of slices, arrays and map
+// values of string types. Elements are not transformed for fields with the
+// shallow tag option, as they may be shared with the source.
+func transformField(v reflect.Value, structTag reflect.StructTag, tagKey string) {
+	options := tagOptions(structTag, tagKey)
+	if options == "" {
+		return
+	}
+	shallow := tagContains(structTag, tagKey, "shallow")
+	for options != "" {
+		var option string
+		option, options, _ = strings.Cut(options, ",")
+		if fn, ok := transforms[option]; ok {
+			transformValue(v, fn, shallow)
+		}
+	}
+}
+
+func transformValue(v reflect.Value, fn func(string) string, shallow bool) {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(fn(v.String()))
+	case reflect.Ptr:
+		if !v.IsNil() && v.Type().Elem().Kind() == reflect.String {
+			// The pointer may reference the source value, so a new one is
+			// set.
+			p := reflect.New(v.Type().Elem())
+			p.Elem().SetString(fn(v.Elem().String()))
+			v.Set(p)
+		}
+	case reflect.Slice, reflect.Array:
+		if shallow || v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			transformValue(v.Index(i), fn, shallow)
+		}
+	case reflect.Map:
+		if shallow || v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		e := reflect.New(v.Type().Elem()).Elem()
+		iter := v.MapRange()
+		for iter.Next() {
+			e.SetIterValue(iter)
+			e.SetString(fn(e.String()))
+			v.SetMapIndex(iter.Key(), e)
+		}
+	}
+}
+
+// title converts the first letter of every word, separated by white space,
+// to title case.
+func title(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	start := true
+	for _, r := range s {
+		if start {
+			r = unicode.ToTitle(r)
+		}
+		start = unicode.IsSpace(r)
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// collapse replaces every sequence of white space with a single space.
+func collapse(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			if !space {
+				b.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type TransformedLevel string
+
+type TransformedUser struct {
+	Name     string            `taint:"name,trim,collapse,lower,title"`
+	Email    string            `taint:"email,trim,lower"`
+	Code     *string           `taint:"code,upper"`
+	Level    TransformedLevel  `taint:"level,trim,upper"`
+	Tags     []string          `taint:"tags,trim,lower"`
+	Roles    [2]string         `taint:"roles,upper"`
+	Labels   map[string]string `taint:"labels,collapse"`
+	Comment  string            `taint:"comment"`
+	Username string            `taint:"username,trim,nonzero"`
+}
+
+func TestTransform(t *testing.T) {
+	src := map[string]interface{}{
+		"name":     "  jOHN \t  ronald\n reuel  TOLKIEN ",
+		"email":    " John@Example.COM ",
+		"code":     "ab-12",
+		"level":    TransformedLevel(" debug "),
+		"tags":     []interface{}{" Go ", "RUST"},
+		"roles":    []string{"admin", "dev"},
+		"labels":   map[string]interface{}{"Team": "core   team", "Zone": " eu  west "},
+		"comment":  "  as  is ",
+		"username": " jrrt ",
+	}
+	var d TransformedUser
+	if err := Inject(src, &d); err != nil {
+		t.Fatal(err)
+	}
+	code := "AB-12"
+	expected := TransformedUser{
+		Name:     "John Ronald Reuel Tolkien",
+		Email:    "john@example.com",
+		Code:     &code,
+		Level:    "DEBUG",
+		Tags:     []string{"go", "rust"},
+		Roles:    [2]string{"ADMIN", "DEV"},
+		Labels:   map[string]string{"Team": "core team", "Zone": " eu west "},
+		Comment:  "  as  is ",
+		Username: "jrrt",
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+}
+
+func TestTransformSourcePointer(t *testing.T) {
+	code := "ab-12"
+	var d TransformedUser
+	if err := Inject(struct{ Code *string }{&code}, &d); err != nil {
+		t.Fatal(err)
+	}
+	if err := Inject(map[string]interface{}{"code": &code}, &d); err != nil {
+		t.Fatal(err)
+	}
+	if *d.Code != "AB-12" {
+		t.Errorf("%T destination %#v is not set to %#v", d.Code, *d.Code, "AB-12")
+	}
+	if code != "ab-12" {
+		t.Errorf("source is changed to %q", code)
+	}
+}
+
+func TestTransformStruct(t *testing.T) {
+	type source struct {
+		Name string
+		Tags []string
+	}
+	type destination struct {
+		Name string   `taint:",trim,upper"`
+		Tags []string `taint:",trim"`
+	}
+	src := source{Name: " a ", Tags: []string{" b "}}
+	var d destination
+	if err := Inject(src, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Name != "A" || !reflect.DeepEqual(d.Tags, []string{"b"}) {
+		t.Errorf("unexpected destination %#v", d)
+	}
+	if src.Tags[0] != " b " {
+		t.Errorf("source is changed to %#v", src)
+	}
+}
+
+func TestTransformShallow(t *testing.T) {
+	type destination struct {
+		Name string   `taint:",shallow,trim"`
+		Tags []string `taint:",shallow,trim"`
+	}
+	src := map[string]interface{}{"Name": " a ", "Tags": []string{" b "}}
+	var d destination
+	if err := Inject(src, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Name != "a" {
+		t.Errorf("%T destination %#v is not set to %#v", d.Name, d.Name, "a")
+	}
+	if d.Tags[0] != " b " {
+		t.Errorf("shared slice element is changed to %q", d.Tags[0])
+	}
+}
+
+func TestTransformBeforeValidation(t *testing.T) {
+	var d TransformedUser
+	err := Inject(map[string]interface{}{"username": "   "}, &d)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("got error %v, want %T", err, verr)
+	}
+	if verr.Path != "Username" {
+		t.Errorf("got error path %q, want %q", verr.Path, "Username")
+	}
+}
+
+func TestTitle(t *testing.T) {
+	for in, out := range map[string]string{
+		"":              "",
+		"hello world":   "Hello World",
+		" hello\tgo ":   " Hello\tGo ",
+		"ǆemal bijedić": "ǅemal Bijedić",
+		"mIxEd":         "MIxEd",
+	} {
+		if got := title(in); got != out {
+			t.Errorf("title(%q) = %q, want %q", in, got, out)
+		}
+	}
+}
+
+func TestCollapse(t *testing.T) {
+	for in, out := range map[string]string{
+		"":           "",
+		"a  b":       "a b",
+		" \t a\n\nb": " a b",
+		"a b ":       "a b ",
+	} {
+		if got := collapse(in); got != out {
+			t.Errorf("collapse(%q) = %q, want %q", in, got, out)
+		}
+	}
+}
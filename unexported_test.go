@@ -0,0 +1,174 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type mixedFields struct {
+	Name    string
+	secret  string
+	Count   int
+	count   int
+	Nested  mixedNested
+	nested  mixedNested
+	Entries []mixedNested
+}
+
+type mixedNested struct {
+	Value string
+	value string
+}
+
+func TestInjectMapToUnexportedFields(t *testing.T) {
+	s := map[string]interface{}{
+		"Name":   "name",
+		"secret": "secret",
+		"Count":  1,
+		"count":  2,
+		"Nested": map[string]interface{}{
+			"Value": "v",
+			"value": "hidden",
+		},
+		"nested": map[string]interface{}{
+			"Value": "v",
+		},
+		"Entries": []interface{}{
+			map[string]interface{}{
+				"Value": "e",
+				"value": "hidden",
+			},
+		},
+	}
+	expected := mixedFields{
+		Name:    "name",
+		Count:   1,
+		Nested:  mixedNested{Value: "v"},
+		Entries: []mixedNested{{Value: "e"}},
+	}
+	var d mixedFields
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+}
+
+func TestInjectStructToStructWithUnexportedFields(t *testing.T) {
+	s := mixedFields{
+		Name:    "name",
+		secret:  "secret",
+		Count:   1,
+		count:   2,
+		Nested:  mixedNested{Value: "v", value: "hidden"},
+		nested:  mixedNested{Value: "v", value: "hidden"},
+		Entries: []mixedNested{{Value: "e", value: "hidden"}},
+	}
+	expected := mixedFields{
+		Name:    "name",
+		Count:   1,
+		Nested:  mixedNested{Value: "v"},
+		Entries: []mixedNested{{Value: "e"}},
+	}
+	var d mixedFields
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+}
+
+func TestInjectUnexportedSourceFieldToExportedField(t *testing.T) {
+	s := struct {
+		name  string
+		Other string
+	}{
+		name:  "hidden",
+		Other: "other",
+	}
+	var d struct {
+		Name  string `taint:"name"`
+		Other string
+	}
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Name != "" {
+		t.Errorf("got name %q from unexported source field", d.Name)
+	}
+	if d.Other != "other" {
+		t.Errorf("got %q, want %q", d.Other, "other")
+	}
+}
+
+func TestInjectStructToMapWithUnexportedFields(t *testing.T) {
+	s := mixedFields{
+		Name:   "name",
+		secret: "secret",
+		Count:  1,
+		count:  2,
+	}
+	var d map[string]interface{}
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"secret", "count", "nested"} {
+		if _, ok := d[key]; ok {
+			t.Errorf("unexpected key %q", key)
+		}
+	}
+	for _, key := range []string{"Name", "Count", "Nested", "Entries"} {
+		if _, ok := d[key]; !ok {
+			t.Errorf("missing key %q", key)
+		}
+	}
+}
+
+func TestUnexportedFieldError(t *testing.T) {
+	in := NewInjector(WithUnexportedFieldError())
+
+	s := map[string]interface{}{
+		"Name": "name",
+		"Nested": map[string]interface{}{
+			"value": "hidden",
+		},
+	}
+	var d mixedFields
+	err := in.Inject(s, &d)
+	var uerr *UnexportedFieldError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("Expected UnexportedFieldError, but got %#v", err)
+	}
+	if uerr.Path != "Nested.value" {
+		t.Errorf("got path %q, want %q", uerr.Path, "Nested.value")
+	}
+
+	s = map[string]interface{}{
+		"Name": "name",
+	}
+	if err := in.Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnexportedFieldErrorFromStruct(t *testing.T) {
+	in := NewInjector(WithUnexportedFieldError())
+	s := mixedFields{Name: "name", secret: "secret"}
+	var d mixedFields
+	err := in.Inject(s, &d)
+	var uerr *UnexportedFieldError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("Expected UnexportedFieldError, but got %#v", err)
+	}
+	if uerr.Path != "secret" {
+		t.Errorf("got path %q, want %q", uerr.Path, "secret")
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Validator is implemented by types that validate their own state. If a
+// destination struct, or a pointer to it, implements Validator, its Validate
+// method is called after all of its fields are injected.
+type Validator interface {
+	Validate() error
+}
+
+var validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
+
+// callValidator calls the Validate method of the struct value if it
+// implements the Validator interface, wrapping the returned error with the
+// path of the value.
+func callValidator(v reflect.Value, path string) error {
+	var validator Validator
+	if v.CanAddr() {
+		validator, _ = v.Addr().Interface().(Validator)
+	}
+	if validator == nil && v.CanInterface() && v.Type().Implements(validatorType) {
+		validator, _ = v.Interface().(Validator)
+	}
+	if validator == nil {
+		return nil
+	}
+	if err := validator.Validate(); err != nil {
+		return &ValidatorError{
+			Path: path,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// validateAssigned calls Validate methods of structs in the value that is
+// assigned to the destination as it is, as they are called when the value is
+// injected.
+func (in *injection) validateAssigned(v reflect.Value, path string) error {
+	if !hasValidators(v.Type()) {
+		return nil
+	}
+	return walkValidators(v, path, in.tagKey, make(map[visit]struct{}), in.validate)
+}
+
+// walkValidators calls the function for structs in the value, after it is
+// called for values of their fields. Values held by interfaces and struct
+// fields with the "-" key name are skipped.
+func walkValidators(v reflect.Value, path, tagKey string, visited map[visit]struct{}, fn func(v reflect.Value, path string) error) error {
+	if key, ok := newVisit(v, nil); ok {
+		if _, ok := visited[key]; ok {
+			return nil
+		}
+		visited[key] = struct{}{}
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return walkValidators(v.Elem(), path, tagKey, visited, fn)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !isExported(f) || keyNameFromTag(f.Tag, tagKey) == "-" {
+				continue
+			}
+			if err := walkValidators(v.Field(i), fieldPath(path, f.Name), tagKey, visited, fn); err != nil {
+				return err
+			}
+		}
+		return fn(v, path)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := walkValidators(v.Index(i), indexPath(path, i), tagKey, visited, fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := walkValidators(iter.Value(), keyPath(path, iter.Key()), tagKey, visited, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validatorTypes caches results of hasValidators.
+var validatorTypes sync.Map // map[reflect.Type]bool
+
+// hasValidators returns true if values of the type may contain structs that
+// implement the Validator interface, not counting values held by interfaces.
+func hasValidators(t reflect.Type) bool {
+	if v, ok := validatorTypes.Load(t); ok {
+		return v.(bool)
+	}
+	has := typeHasValidators(t, make(map[reflect.Type]struct{}))
+	validatorTypes.Store(t, has)
+	return has
+}
+
+func typeHasValidators(t reflect.Type, seen map[reflect.Type]struct{}) bool {
+	if _, ok := seen[t]; ok {
+		return false
+	}
+	seen[t] = struct{}{}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return typeHasValidators(t.Elem(), seen)
+	case reflect.Struct:
+		if t.Implements(validatorType) || reflect.PtrTo(t).Implements(validatorType) {
+			return true
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); isExported(f) && typeHasValidators(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateField checks the injected field value against validation rules
+// from struct tag options. Supported rules are:
+//
+//	nonzero        value must not be the zero value of its type
+//	min=N          number must be at least N, or length must be at least N
+//	max=N          number must be at most N, or length must be at most N
+//	len=N          length of a string, slice, array or map must be N
+//	oneof=a|b|c    value must be one of the listed values
+//	regexp=PATTERN string must match the regular expression
+//
+// Lengths of strings are counted in runes. Regular expression patterns
+// must not contain commas as they are used to separate tag options.
+func validateField(v reflect.Value, structTag reflect.StructTag, tagKey, path string) error {
+	options := tagOptions(structTag, tagKey)
+	for options != "" {
+		var option string
+		option, options, _ = strings.Cut(options, ",")
+		rule, arg := option, ""
+		if i := strings.IndexByte(option, '='); i >= 0 {
+			rule, arg = option[:i], option[i+1:]
+		}
+		var ok bool
+		var err error
+		switch rule {
+		case "nonzero":
+			ok = !isZero(v)
+		case "min":
+			ok, err = compareBound(v, arg, func(c int) bool { return c >= 0 })
+		case "max":
+			ok, err = compareBound(v, arg, func(c int) bool { return c <= 0 })
+		case "len":
+			ok, err = compareLen(v, arg)
+		case "oneof":
+			ok, err = oneOf(v, arg)
+		case "regexp":
+			ok, err = matchRegexp(v, arg)
+		default:
+			continue
+		}
+		if err != nil {
+			return &InvalidRuleError{
+				Path: path,
+				Rule: option,
+				Err:  err,
+			}
+		}
+		if !ok {
+			return &ValidationError{
+				Path:  path,
+				Rule:  option,
+				Value: v.Interface(),
+			}
+		}
+	}
+	return nil
+}
+
+func isZero(v reflect.Value) bool {
+	return !v.IsValid() || v.IsZero()
+}
+
+// compareBound compares a numeric value or a length of the value with the
+// rule argument, passing the comparison result (-1, 0 or 1) to the check
+// function. Nil pointers are not compared.
+func compareBound(v reflect.Value, arg string, check func(int) bool) (bool, error) {
+	v = reflect.Indirect(v)
+	if !v.IsValid() {
+		return true, nil
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return false, err
+		}
+		return check(compareInt64(v.Int(), n)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return false, err
+		}
+		return check(compareUint64(v.Uint(), n)), nil
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return false, err
+		}
+		return check(compareFloat64(v.Float(), n)), nil
+	}
+	l, ok := length(v)
+	if !ok {
+		return false, fmt.Errorf("unsupported type %s", v.Type())
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return false, err
+	}
+	return check(compareInt64(int64(l), int64(n))), nil
+}
+
+func compareLen(v reflect.Value, arg string) (bool, error) {
+	v = reflect.Indirect(v)
+	if !v.IsValid() {
+		return true, nil
+	}
+	l, ok := length(v)
+	if !ok {
+		return false, fmt.Errorf("unsupported type %s", v.Type())
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return false, err
+	}
+	return l == n, nil
+}
+
+func length(v reflect.Value) (int, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(v.String()), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len(), true
+	}
+	return 0, false
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloat64(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func oneOf(v reflect.Value, arg string) (bool, error) {
+	v = reflect.Indirect(v)
+	if !v.IsValid() {
+		return true, nil
+	}
+	var s string
+	switch v.Kind() {
+	case reflect.String:
+		s = v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		s = strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		s = strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case reflect.Bool:
+		s = strconv.FormatBool(v.Bool())
+	default:
+		return false, fmt.Errorf("unsupported type %s", v.Type())
+	}
+	for _, e := range strings.Split(arg, "|") {
+		if e == s {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+var regexpCache sync.Map // map[string]*regexp.Regexp
+
+func matchRegexp(v reflect.Value, pattern string) (bool, error) {
+	v = reflect.Indirect(v)
+	if !v.IsValid() {
+		return true, nil
+	}
+	if v.Kind() != reflect.String {
+		return false, fmt.Errorf("unsupported type %s", v.Type())
+	}
+	var re *regexp.Regexp
+	if r, ok := regexpCache.Load(pattern); ok {
+		re = r.(*regexp.Regexp)
+	} else {
+		var err error
+		re, err = regexp.Compile(pattern)
+		if err != nil {
+			return false, err
+		}
+		regexpCache.Store(pattern, re)
+	}
+	return re.MatchString(v.String()), nil
+}
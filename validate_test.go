@@ -0,0 +1,368 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"errors"
+	"testing"
+)
+
+type ValidatedServer struct {
+	Host    string   `taint:"host,nonzero"`
+	Port    int      `taint:"port,min=1,max=65535"`
+	Scheme  string   `taint:"scheme,oneof=http|https"`
+	Code    string   `taint:"code,len=3"`
+	Name    string   `taint:"name,regexp=^[a-z]+$"`
+	Tags    []string `taint:"tags,max=2"`
+	Timeout float64  `taint:"timeout,min=0.5"`
+}
+
+type ValidatedConfig struct {
+	Servers []ValidatedServer
+}
+
+func TestValidateValid(t *testing.T) {
+	s := map[string]interface{}{
+		"host":    "localhost",
+		"port":    8080,
+		"scheme":  "https",
+		"code":    "abc",
+		"name":    "web",
+		"tags":    []string{"a", "b"},
+		"timeout": 1.5,
+	}
+	var d ValidatedServer
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Port != 8080 || d.Timeout != 1.5 {
+		t.Errorf("unexpected destination %#v", d)
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  map[string]interface{}
+		path string
+		rule string
+	}{
+		{
+			name: "nonzero",
+			src:  map[string]interface{}{"host": ""},
+			path: "Host",
+			rule: "nonzero",
+		},
+		{
+			name: "min",
+			src:  map[string]interface{}{"port": 0},
+			path: "Port",
+			rule: "min=1",
+		},
+		{
+			name: "max",
+			src:  map[string]interface{}{"port": 70000},
+			path: "Port",
+			rule: "max=65535",
+		},
+		{
+			name: "oneof",
+			src:  map[string]interface{}{"scheme": "ftp"},
+			path: "Scheme",
+			rule: "oneof=http|https",
+		},
+		{
+			name: "len",
+			src:  map[string]interface{}{"code": "abcd"},
+			path: "Code",
+			rule: "len=3",
+		},
+		{
+			name: "regexp",
+			src:  map[string]interface{}{"name": "Web1"},
+			path: "Name",
+			rule: "regexp=^[a-z]+$",
+		},
+		{
+			name: "max length",
+			src:  map[string]interface{}{"tags": []string{"a", "b", "c"}},
+			path: "Tags",
+			rule: "max=2",
+		},
+		{
+			name: "min float",
+			src:  map[string]interface{}{"timeout": 0.1},
+			path: "Timeout",
+			rule: "min=0.5",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var d ValidatedServer
+			err := Inject(tc.src, &d)
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Expected ValidationError, but got %#v", err)
+			}
+			if verr.Path != tc.path {
+				t.Errorf("got path %q, want %q", verr.Path, tc.path)
+			}
+			if verr.Rule != tc.rule {
+				t.Errorf("got rule %q, want %q", verr.Rule, tc.rule)
+			}
+		})
+	}
+}
+
+func TestValidateRuleInPlaceOfKeyName(t *testing.T) {
+	type Config struct {
+		Port int    `taint:"min=3"`
+		Name string `taint:"regexp=^a"`
+	}
+	for _, tc := range []struct {
+		name string
+		src  map[string]interface{}
+		path string
+		rule string
+	}{
+		{
+			name: "min",
+			src:  map[string]interface{}{"Port": 2, "Name": "api"},
+			path: "Port",
+			rule: "min=3",
+		},
+		{
+			name: "regexp",
+			src:  map[string]interface{}{"Port": 3, "Name": "web"},
+			path: "Name",
+			rule: "regexp=^a",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var d Config
+			err := Inject(tc.src, &d)
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Expected ValidationError, but got %#v", err)
+			}
+			if verr.Path != tc.path {
+				t.Errorf("got path %q, want %q", verr.Path, tc.path)
+			}
+			if verr.Rule != tc.rule {
+				t.Errorf("got rule %q, want %q", verr.Rule, tc.rule)
+			}
+		})
+	}
+	var d Config
+	if err := Inject(map[string]interface{}{"Port": 3, "Name": "api"}, &d); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateNestedPath(t *testing.T) {
+	s := map[string]interface{}{
+		"Servers": []interface{}{
+			map[string]interface{}{"host": "a", "port": 80},
+			map[string]interface{}{"host": "b", "port": -1},
+		},
+	}
+	var d ValidatedConfig
+	err := Inject(s, &d)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected ValidationError, but got %#v", err)
+	}
+	if verr.Path != "Servers[1].Port" {
+		t.Errorf("got path %q, want %q", verr.Path, "Servers[1].Port")
+	}
+	if verr.Value != -1 {
+		t.Errorf("got value %#v, want %#v", verr.Value, -1)
+	}
+}
+
+func TestValidateFromStruct(t *testing.T) {
+	s := struct {
+		Host string
+		Port int
+	}{
+		Host: "localhost",
+		Port: 0,
+	}
+	var d struct {
+		Host string `taint:",nonzero"`
+		Port int    `taint:",min=1"`
+	}
+	err := Inject(s, &d)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected ValidationError, but got %#v", err)
+	}
+	if verr.Path != "Port" {
+		t.Errorf("got path %q, want %q", verr.Path, "Port")
+	}
+}
+
+func TestInvalidRuleError(t *testing.T) {
+	s := map[string]interface{}{
+		"Port": 10,
+	}
+	var d struct {
+		Port int `taint:",min=one"`
+	}
+	err := Inject(s, &d)
+	var rerr *InvalidRuleError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("Expected InvalidRuleError, but got %#v", err)
+	}
+	if rerr.Rule != "min=one" {
+		t.Errorf("got rule %q, want %q", rerr.Rule, "min=one")
+	}
+}
+
+type Range struct {
+	Min int
+	Max int
+}
+
+var errInvalidRange = errors.New("min is greater than max")
+
+func (r Range) Validate() error {
+	if r.Min > r.Max {
+		return errInvalidRange
+	}
+	return nil
+}
+
+type Limits struct {
+	Default Range
+	Ranges  []Range
+}
+
+func TestValidator(t *testing.T) {
+	s := map[string]interface{}{
+		"Min": 3,
+		"Max": 1,
+	}
+	var d Range
+	err := Inject(s, &d)
+	var verr *ValidatorError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected ValidatorError, but got %#v", err)
+	}
+	if verr.Path != "" {
+		t.Errorf("got path %q, want empty path", verr.Path)
+	}
+	if !errors.Is(err, errInvalidRange) {
+		t.Errorf("got error %v, want %v", err, errInvalidRange)
+	}
+}
+
+func TestValidatorNested(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  interface{}
+		path string
+	}{
+		{
+			name: "struct field from map",
+			src: map[string]interface{}{
+				"Default": map[string]interface{}{"Min": 2, "Max": 1},
+			},
+			path: "Default",
+		},
+		{
+			name: "slice element from map",
+			src: map[string]interface{}{
+				"Ranges": []interface{}{
+					map[string]interface{}{"Min": 1, "Max": 2},
+					map[string]interface{}{"Min": 5, "Max": 2},
+				},
+			},
+			path: "Ranges[1]",
+		},
+		{
+			name: "struct field from struct",
+			src: struct {
+				Default struct{ Min, Max int }
+			}{
+				Default: struct{ Min, Max int }{Min: 2, Max: 1},
+			},
+			path: "Default",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var d Limits
+			err := Inject(tc.src, &d)
+			var verr *ValidatorError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Expected ValidatorError, but got %#v", err)
+			}
+			if verr.Path != tc.path {
+				t.Errorf("got path %q, want %q", verr.Path, tc.path)
+			}
+			if !errors.Is(err, errInvalidRange) {
+				t.Errorf("got error %v, want %v", err, errInvalidRange)
+			}
+		})
+	}
+}
+
+func TestValidatorValid(t *testing.T) {
+	s := map[string]interface{}{
+		"Default": map[string]interface{}{"Min": 1, "Max": 2},
+		"Ranges": []interface{}{
+			map[string]interface{}{"Min": 1, "Max": 1},
+		},
+	}
+	var d Limits
+	if err := Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidatorAssigned(t *testing.T) {
+	type pointers struct {
+		P *Range
+	}
+	type arrays struct {
+		A [2]Range
+	}
+	for _, tc := range []struct {
+		name string
+		src  interface{}
+		dst  interface{}
+		path string
+	}{
+		{
+			name: "pointer field",
+			src:  pointers{P: &Range{Min: 2, Max: 1}},
+			dst:  new(pointers),
+			path: "P",
+		},
+		{
+			name: "array",
+			src:  [1]Range{{Min: 2, Max: 1}},
+			dst:  new([1]Range),
+			path: "[0]",
+		},
+		{
+			name: "array field",
+			src:  arrays{A: [2]Range{{Min: 1, Max: 2}, {Min: 2, Max: 1}}},
+			dst:  new(arrays),
+			path: "A[1]",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Inject(tc.src, tc.dst)
+			var verr *ValidatorError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Expected ValidatorError, but got %#v", err)
+			}
+			if verr.Path != tc.path {
+				t.Errorf("got path %q, want %q", verr.Path, tc.path)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import "reflect"
+
+// DefaultVariantKey is the default key of the source value that holds the
+// name of the variant registered with Injector.RegisterVariant.
+const DefaultVariantKey = "type"
+
+// WithVariantKey sets the key of source maps and Source values that holds the
+// name of the variant of interface destinations. The default is
+// DefaultVariantKey.
+func WithVariantKey(key string) Option {
+	return func(in *Injector) {
+		in.variantKey = key
+	}
+}
+
+// RegisterVariant registers the type of the example value as the variant with
+// the name of the interface type, provided as a nil pointer to the interface.
+// When the destination is an interface with registered variants and the
+// source is a map or a Source, the variant is selected by the name in the
+// source under the variant key, and the source is injected into a new value
+// of the variant type that is assigned to the destination. If the example
+// value type does not implement the interface, the pointer to it is used.
+//
+// RegisterVariant panics if the interface is not an interface type or if
+// the variant type does not implement it.
+func (in *Injector) RegisterVariant(iface interface{}, name string, example interface{}) {
+	ifaceType := typeOf(iface)
+	if ifaceType == nil || ifaceType.Kind() != reflect.Interface {
+		panic("taint: register variant " + name + " of non-interface type " + typeString(ifaceType))
+	}
+	t := reflect.TypeOf(example)
+	if t == nil {
+		panic("taint: register variant " + name + " of " + ifaceType.String() + " with nil type")
+	}
+	if !t.Implements(ifaceType) {
+		if !reflect.PtrTo(t).Implements(ifaceType) {
+			panic("taint: register variant " + name + " type " + t.String() + " does not implement " + ifaceType.String())
+		}
+		t = reflect.PtrTo(t)
+	}
+
+	in.variantsMu.Lock()
+	defer in.variantsMu.Unlock()
+
+	if in.variants == nil {
+		in.variants = make(map[reflect.Type]map[string]reflect.Type)
+	}
+	if in.variants[ifaceType] == nil {
+		in.variants[ifaceType] = make(map[string]reflect.Type)
+	}
+	in.variants[ifaceType][name] = t
+}
+
+// hasVariants returns true if variants of the interface type are registered.
+func (in *Injector) hasVariants(ifaceType reflect.Type) bool {
+	in.variantsMu.RLock()
+	defer in.variantsMu.RUnlock()
+
+	return in.variants[ifaceType] != nil
+}
+
+// variantType returns the variant type of the interface type that is
+// registered with the name.
+func (in *Injector) variantType(ifaceType reflect.Type, name string) (reflect.Type, bool) {
+	in.variantsMu.RLock()
+	defer in.variantsMu.RUnlock()
+
+	t, ok := in.variants[ifaceType][name]
+	return t, ok
+}
+
+// injectVariant injects the source map or Source into a new value of the
+// variant type selected by the variant key, and assigns it to the interface
+// destination. It returns false if the destination is not an interface with
+// registered variants or if the source is not a map or a Source.
+func (in *injection) injectVariant(srcValue, dstValue reflect.Value, path string) (handled bool, err error) {
+	if dstValue.Kind() != reflect.Interface {
+		return false, nil
+	}
+	if !in.hasVariants(dstValue.Type()) {
+		return false, nil
+	}
+	var nameValue reflect.Value
+	var ok bool
+	if src, isSource := asSource(srcValue); isSource {
+		var v interface{}
+		v, ok = src.Lookup(in.variantKey)
+		nameValue = reflect.ValueOf(v)
+	} else {
+		v := reflect.Indirect(srcValue)
+		if v.Kind() != reflect.Map {
+			return false, nil
+		}
+		key, isKey := stringKey(in.variantKey, v.Type().Key())
+		if !isKey {
+			return false, nil
+		}
+		nameValue = v.MapIndex(key)
+		ok = nameValue.IsValid()
+	}
+	var name string
+	if ok && nameValue.IsValid() {
+		if nameValue.Kind() == reflect.Interface {
+			nameValue = nameValue.Elem()
+		}
+		if nameValue.IsValid() {
+			ok = convertBasic(nameValue, reflect.ValueOf(&name).Elem())
+		}
+	}
+	if !ok || name == "" {
+		return true, &VariantError{
+			Path: path,
+			Type: dstValue.Type(),
+			Key:  in.variantKey,
+		}
+	}
+	t, ok := in.variantType(dstValue.Type(), name)
+	if !ok {
+		return true, &VariantError{
+			Path: path,
+			Type: dstValue.Type(),
+			Key:  in.variantKey,
+			Name: name,
+		}
+	}
+	if t.Kind() == reflect.Ptr {
+		v := reflect.New(t.Elem())
+		if err := in.inject(srcValue, v, path); err != nil {
+			return true, err
+		}
+		dstValue.Set(v)
+		return true, nil
+	}
+	v := reflect.New(t)
+	if err := in.inject(srcValue, v, path); err != nil {
+		return true, err
+	}
+	dstValue.Set(v.Elem())
+	return true, nil
+}
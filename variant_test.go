@@ -0,0 +1,234 @@
+// Copyright (c) 2015, Janoš Guljaš <janos@resenje.org>
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+type StorageConfig interface {
+	StorageType() string
+}
+
+type S3Config struct {
+	Bucket string `taint:"bucket"`
+	Region string `taint:"region"`
+}
+
+func (S3Config) StorageType() string { return "s3" }
+
+type DiskConfig struct {
+	Path string `taint:"path"`
+}
+
+func (*DiskConfig) StorageType() string { return "disk" }
+
+type StorageSettings struct {
+	Name     string          `taint:"name"`
+	Storage  StorageConfig   `taint:"storage"`
+	Replicas []StorageConfig `taint:"replicas"`
+}
+
+func newStorageInjector(opts ...Option) *Injector {
+	in := NewInjector(opts...)
+	in.RegisterVariant((*StorageConfig)(nil), "s3", S3Config{})
+	in.RegisterVariant((*StorageConfig)(nil), "disk", DiskConfig{})
+	return in
+}
+
+func TestInjectVariant(t *testing.T) {
+	s := map[string]interface{}{
+		"name": "backups",
+		"storage": map[string]interface{}{
+			"type":   "s3",
+			"bucket": "backups",
+			"region": "eu-central-1",
+		},
+		"replicas": []interface{}{
+			map[interface{}]interface{}{
+				"type": "disk",
+				"path": "/var/backups",
+			},
+		},
+	}
+	expected := StorageSettings{
+		Name: "backups",
+		Storage: S3Config{
+			Bucket: "backups",
+			Region: "eu-central-1",
+		},
+		Replicas: []StorageConfig{
+			&DiskConfig{
+				Path: "/var/backups",
+			},
+		},
+	}
+	var d StorageSettings
+	if err := newStorageInjector().Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+}
+
+func TestInjectVariantKey(t *testing.T) {
+	s := map[string]interface{}{
+		"storage": map[string]interface{}{
+			"kind": "disk",
+			"path": "/data",
+		},
+	}
+	expected := StorageSettings{
+		Storage: &DiskConfig{
+			Path: "/data",
+		},
+	}
+	var d StorageSettings
+	if err := newStorageInjector(WithVariantKey("kind")).Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+}
+
+func TestInjectVariantSource(t *testing.T) {
+	s := configNode{
+		props: map[string]string{
+			"storage.type":   "s3",
+			"storage.bucket": "backups",
+		},
+		lookups: new([]string),
+	}
+	expected := StorageSettings{
+		Storage: S3Config{
+			Bucket: "backups",
+		},
+	}
+	var d StorageSettings
+	if err := newStorageInjector().Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, expected)
+	}
+}
+
+func TestInjectVariantAssignable(t *testing.T) {
+	s := StorageSettings{
+		Storage: S3Config{
+			Bucket: "backups",
+		},
+		Replicas: []StorageConfig{
+			&DiskConfig{
+				Path: "/data",
+			},
+		},
+	}
+	var d StorageSettings
+	if err := newStorageInjector().Inject(s, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, s) {
+		t.Errorf("%T destination %#v is not set to %#v", d, d, s)
+	}
+}
+
+func TestInjectVariantErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		storage interface{}
+		variant string
+	}{
+		{
+			name: "missing key",
+			storage: map[string]interface{}{
+				"bucket": "backups",
+			},
+		},
+		{
+			name: "unknown variant",
+			storage: map[string]interface{}{
+				"type": "gcs",
+			},
+			variant: "gcs",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var d StorageSettings
+			err := newStorageInjector().Inject(map[string]interface{}{"storage": tc.storage}, &d)
+			var verr *VariantError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Expected VariantError, but got %#v", err)
+			}
+			if verr.Path != "Storage" {
+				t.Errorf("Expected VariantError Path Storage, but got %#v", verr.Path)
+			}
+			if verr.Name != tc.variant {
+				t.Errorf("Expected VariantError Name %#v, but got %#v", tc.variant, verr.Name)
+			}
+		})
+	}
+}
+
+func TestInjectVariantConcurrentRegister(t *testing.T) {
+	in := newStorageInjector()
+	s := map[string]interface{}{
+		"storage": map[string]interface{}{
+			"type": "disk",
+			"path": "/var/backups",
+		},
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			in.RegisterVariant((*StorageConfig)(nil), "s3-"+strconv.Itoa(i), S3Config{})
+		}()
+		go func() {
+			defer wg.Done()
+			var d StorageSettings
+			if err := in.Inject(s, &d); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRegisterVariantPanic(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		iface   interface{}
+		example interface{}
+	}{
+		{
+			name:    "non-interface",
+			iface:   S3Config{},
+			example: S3Config{},
+		},
+		{
+			name:    "not implemented",
+			iface:   (*StorageConfig)(nil),
+			example: "s3",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected panic")
+				}
+			}()
+			NewInjector().RegisterVariant(tc.iface, "s3", tc.example)
+		})
+	}
+}